@@ -0,0 +1,148 @@
+package main
+
+import (
+  "fmt"
+  "os"
+  "flag"
+  "errors"
+  "encoding/json"
+  "github.com/dschnare/doctag/parse"
+  "github.com/dschnare/doctag/hierarchy"
+)
+
+// The exit codes of the command.
+const (
+  exitError = 1
+  exitUsage = 2
+  exitParse = 3
+  exitTransform = 4
+  exitIO = 5
+)
+
+// Returned by commands that already reported their errors.
+var errReported = errors.New("Errors were reported")
+
+var (
+  // The exit code of the first error (or warning with -warnings-as-errors) reported by the current run.
+  reportedCode int
+  // The number of warnings reported by the current run.
+  warnings int
+)
+
+// An error that exits the command with a specific code.
+type codeError struct {
+  code int
+  err error
+}
+
+func (e *codeError) Error() string {
+  return e.err.Error()
+}
+
+func (e *codeError) Unwrap() error {
+  return e.err
+}
+
+// Reports the error and exits with its exit code.
+func fail(err error) {
+  if err != errReported {
+    reportError(err)
+  }
+  if reportedCode == 0 {
+    reportedCode = exitError
+  }
+  os.Exit(reportedCode)
+}
+
+// Reports an error to stderr without stopping, as a JSON diagnostic with -diagnostics=json.
+func reportError(err error) {
+  var diagnostic *parse.Diagnostic
+  if !errors.As(err, &diagnostic) {
+    diagnostic = &parse.Diagnostic{Code: "error", Severity: parse.SeverityError, Message: err.Error()}
+  } else if len(diagnostic.File) == 0 && diagnostic.Line > 0 && len(fileName) > 0 && !isPiped(os.Stdin) {
+    diagnostic.File = fileName
+  }
+
+  if reportedCode == 0 {
+    reportedCode = exitCode(err)
+  }
+  writeDiagnostic(diagnostic)
+}
+
+// Reports a warning of the parser or transformer. Warnings are reported as errors with -warnings-as-errors.
+func reportWarning(warning *parse.Diagnostic) {
+  warnings++
+  if warningsAsErrors {
+    warning.Severity = parse.SeverityError
+    if reportedCode == 0 {
+      reportedCode = exitCode(warning)
+    }
+  }
+  writeDiagnostic(warning)
+}
+
+// Writes the diagnostic to stderr, as a single line of JSON with -diagnostics=json
+// or as text formatted by formatDiagnostic.
+func writeDiagnostic(diagnostic *parse.Diagnostic) {
+  if diagnostics == "json" {
+    json.NewEncoder(os.Stderr).Encode(diagnostic)
+    return
+  }
+  fmt.Fprintln(os.Stderr, formatDiagnostic(diagnostic))
+}
+
+// Formats a diagnostic as "file:line:column: severity: message".
+func formatDiagnostic(diagnostic *parse.Diagnostic) string {
+  location := "doctag"
+  if diagnostic.Line > 0 && len(diagnostic.File) > 0 {
+    location = fmt.Sprintf("%v:%v:%v", diagnostic.File, diagnostic.Line, diagnostic.Column)
+  } else if diagnostic.Line > 0 {
+    location = fmt.Sprintf("<stdin>:%v:%v", diagnostic.Line, diagnostic.Column)
+  } else if len(diagnostic.File) > 0 {
+    location = diagnostic.File
+  }
+
+  return fmt.Sprintf("%v: %v: %v", location, diagnostic.Severity, diagnostic.Message)
+}
+
+// Returns the exit code for an error: usage errors, parse errors (of doctag or JSON documents),
+// transform errors and I/O errors have their own codes.
+func exitCode(err error) int {
+  var (
+    coded *codeError
+    diagnostic *parse.Diagnostic
+    pathErr *os.PathError
+    linkErr *os.LinkError
+    syscallErr *os.SyscallError
+    syntaxErr *json.SyntaxError
+    typeErr *json.UnmarshalTypeError
+  )
+
+  if errors.As(err, &coded) {
+    return coded.code
+  } else if errors.As(err, &diagnostic) {
+    switch diagnostic.Code {
+    case parse.CodeInvalidConfig:
+      return exitUsage
+    case parse.CodeRead:
+      return exitIO
+    case hierarchy.CodeInvalidPath, hierarchy.CodeEmptyPath, hierarchy.CodeInvalidIndex, hierarchy.CodeInvalidType,
+      hierarchy.CodeInvalidValue, hierarchy.CodeConflict, hierarchy.CodeDuplicate:
+      return exitTransform
+    }
+    return exitParse
+  } else if errors.As(err, &pathErr) || errors.As(err, &linkErr) || errors.As(err, &syscallErr) {
+    return exitIO
+  } else if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+    return exitParse
+  }
+
+  return exitError
+}
+
+// Reports a usage error with the usage message and exits.
+func usageError(message string) {
+  fmt.Fprintf(os.Stderr, "doctag: %v\n", message)
+  flag.Usage()
+  os.Exit(exitUsage)
+}
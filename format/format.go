@@ -0,0 +1,91 @@
+/*
+Package format writes the results of the hierarchy transformer (or any value
+made of maps, slices and scalars) in formats other than JSON.
+
+Map keys are written in sorted order, except for the keys of a
+*hierarchy.OrderedMap, which are written in order.
+*/
+package format
+
+import (
+  "fmt"
+  "regexp"
+  "strconv"
+  "strings"
+  "unicode"
+  "unicode/utf8"
+  "encoding/json"
+)
+
+// Formats a non-string scalar value.
+func scalarString(value interface{}) (string, error) {
+  switch v := value.(type) {
+  case nil:
+    return "null",nil
+  case bool:
+    return strconv.FormatBool(v),nil
+  case int:
+    return strconv.Itoa(v),nil
+  case int64:
+    return strconv.FormatInt(v, 10),nil
+  case float64:
+    return strconv.FormatFloat(v, 'g', -1, 64),nil
+  case json.Number:
+    return v.String(),nil
+  }
+  return "",fmt.Errorf("Cannot format a value of type %T", value)
+}
+
+// Matches strings that YAML (and people) would read as something other than a string.
+var typedPattern = regexp.MustCompile(`^(?i:~|null|true|false|yes|no|on|off|y|n|[-+]?(\.inf|\.nan)|[-+]?[0-9][0-9_]*(\.[0-9_]*)?([eE][-+]?[0-9]+)?|[-+]?\.[0-9]+([eE][-+]?[0-9]+)?|0x[0-9a-f_]+|0o?[0-7_]+|[0-9]+(:[0-5]?[0-9])+(\.[0-9_]*)?|[0-9]{4}-[0-9]{1,2}-[0-9]{1,2}.*)$`)
+
+// Reports whether str would be read as a number, boolean, null or date when unquoted.
+func looksTyped(str string) bool {
+  return typedPattern.MatchString(str)
+}
+
+// Reports whether str is valid UTF-8 without control characters (other than the ones in allowed).
+func printable(str string, allowed string) bool {
+  if !utf8.ValidString(str) {
+    return false
+  }
+  for _,r := range str {
+    if strings.ContainsRune(allowed, r) {
+      continue
+    }
+    if unicode.IsControl(r) || r == '\uFEFF' || r == '\u2028' || r == '\u2029' {
+      return false
+    }
+  }
+  return true
+}
+
+// Double quotes str using escapes understood by both YAML and TOML.
+func quote(str string) string {
+  var b strings.Builder
+
+  b.WriteByte('"')
+  for _,r := range str {
+    switch r {
+    case '"':
+      b.WriteString(`\"`)
+    case '\\':
+      b.WriteString(`\\`)
+    case '\n':
+      b.WriteString(`\n`)
+    case '\t':
+      b.WriteString(`\t`)
+    case '\r':
+      b.WriteString(`\r`)
+    default:
+      if r == utf8.RuneError || unicode.IsControl(r) || r == '\uFEFF' || r == '\u2028' || r == '\u2029' {
+        fmt.Fprintf(&b, `\u%04X`, r)
+      } else {
+        b.WriteRune(r)
+      }
+    }
+  }
+  b.WriteByte('"')
+
+  return b.String()
+}
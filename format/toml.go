@@ -0,0 +1,189 @@
+package format
+
+import (
+  "io"
+  "fmt"
+  "math"
+  "bufio"
+  "regexp"
+  "strconv"
+  "strings"
+  "github.com/dschnare/doctag/hierarchy"
+)
+
+// WriteTOML writes value as a TOML document. The value must be a map.
+// Nested maps are written as tables and slices of maps as arrays of tables,
+// other slices are written as arrays. Multi-line strings are written as
+// multi-line literal strings when possible, other strings as basic strings.
+// TOML has no null value, so nil values result in an error.
+func WriteTOML(writer io.Writer, value interface{}) error {
+  if _,_,ok := hierarchy.AsMap(value); !ok {
+    return fmt.Errorf("Cannot write a %T as a TOML document, expected a map", value)
+  }
+
+  buff := bufio.NewWriter(writer)
+  t := &tomlWriter{buff: buff}
+
+  if err := t.writeTable(value, nil, false); err != nil {
+    return err
+  }
+
+  return buff.Flush()
+}
+
+type tomlWriter struct {
+  buff *bufio.Writer
+  // Whether anything was written yet, used to separate tables with an empty line.
+  written bool
+}
+
+// Writes the table m found at path. The header is written when the table has entries
+// of its own or is empty (so that it exists), and always for an item of an array of tables.
+func (t *tomlWriter) writeTable(table interface{}, path []string, arrayItem bool) error {
+  m,keys,_ := hierarchy.AsMap(table)
+  entries := make([]string, 0, len(keys))
+  tables := make([]string, 0, len(keys))
+
+  for _,key := range keys {
+    if isTable(m[key]) || isArrayOfTables(m[key]) {
+      tables = append(tables, key)
+    } else {
+      entries = append(entries, key)
+    }
+  }
+
+  if len(path) > 0 && (arrayItem || len(entries) > 0 || len(m) == 0) {
+    if t.written {
+      t.buff.WriteString("\n")
+    }
+    if arrayItem {
+      t.buff.WriteString("[[" + tomlPath(path) + "]]\n")
+    } else {
+      t.buff.WriteString("[" + tomlPath(path) + "]\n")
+    }
+    t.written = true
+  }
+
+  for _,key := range entries {
+    text,err := tomlValue(m[key], false)
+    if err != nil {
+      return fmt.Errorf("%v: %v", tomlPath(append(path, key)), err.Error())
+    }
+    t.buff.WriteString(tomlKey(key) + " = " + text + "\n")
+    t.written = true
+  }
+
+  for _,key := range tables {
+    subpath := append(path[:len(path):len(path)], key)
+
+    if isTable(m[key]) {
+      if err := t.writeTable(m[key], subpath, false); err != nil {
+        return err
+      }
+      continue
+    }
+
+    for _,item := range m[key].([]interface{}) {
+      if err := t.writeTable(item, subpath, true); err != nil {
+        return err
+      }
+      t.written = true
+    }
+  }
+
+  return nil
+}
+
+func isTable(value interface{}) bool {
+  _,_,ok := hierarchy.AsMap(value)
+  return ok
+}
+
+// Reports whether value is a non-empty slice of maps only.
+func isArrayOfTables(value interface{}) bool {
+  seq,ok := value.([]interface{})
+  if !ok || len(seq) == 0 {
+    return false
+  }
+  for _,item := range seq {
+    if !isTable(item) {
+      return false
+    }
+  }
+  return true
+}
+
+// Formats a value written after "key = ". Inline values (in arrays and inline tables) are always single line.
+func tomlValue(value interface{}, inline bool) (string, error) {
+  switch v := value.(type) {
+  case string:
+    if !inline && isLiteralString(v) {
+      // The line break following the opening delimiter is not part of the string.
+      return "'''\n" + v + "'''",nil
+    }
+    return quote(v),nil
+  case map[string]interface{}, *hierarchy.OrderedMap:
+    m,keys,_ := hierarchy.AsMap(v)
+    items := make([]string, 0, len(keys))
+    for _,key := range keys {
+      text,err := tomlValue(m[key], true)
+      if err != nil {
+        return "",err
+      }
+      items = append(items, tomlKey(key) + " = " + text)
+    }
+    if len(items) == 0 {
+      return "{}",nil
+    }
+    return "{ " + strings.Join(items, ", ") + " }",nil
+  case []interface{}:
+    items := make([]string, 0, len(v))
+    for _,item := range v {
+      text,err := tomlValue(item, true)
+      if err != nil {
+        return "",err
+      }
+      items = append(items, text)
+    }
+    return "[" + strings.Join(items, ", ") + "]",nil
+  case nil:
+    return "",fmt.Errorf("TOML cannot represent a null value")
+  case float64:
+    switch {
+    case math.IsNaN(v):
+      return "nan",nil
+    case math.IsInf(v, 1):
+      return "inf",nil
+    case math.IsInf(v, -1):
+      return "-inf",nil
+    }
+    text := strconv.FormatFloat(v, 'g', -1, 64)
+    if !strings.ContainsAny(text, ".eE") {
+      text += ".0"
+    }
+    return text,nil
+  }
+  return scalarString(value)
+}
+
+// Reports whether str is a multi-line string that can be written as a multi-line literal string.
+func isLiteralString(str string) bool {
+  return strings.Contains(str, "\n") && !strings.Contains(str, "'''") && !strings.HasSuffix(str, "'") && printable(str, "\t\n")
+}
+
+var bareKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func tomlKey(key string) string {
+  if bareKeyPattern.MatchString(key) {
+    return key
+  }
+  return quote(key)
+}
+
+func tomlPath(path []string) string {
+  keys := make([]string, len(path))
+  for k,key := range path {
+    keys[k] = tomlKey(key)
+  }
+  return strings.Join(keys, ".")
+}
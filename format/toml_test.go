@@ -0,0 +1,67 @@
+package format
+
+import (
+  "testing"
+  "bytes"
+)
+
+func TestWriteTOML(t *testing.T) {
+  value := map[string]interface{}{
+    "page": map[string]interface{}{
+      "title": "Today's News",
+      "content": "\nFirst line\nSecond line\n",
+      "keywords": []interface{}{"awesome", "stuff"},
+      "links": []interface{}{
+        map[string]interface{}{"rel": "next", "href": "http://my.domain.com/next.html"},
+        map[string]interface{}{"rel": "prev", "meta": map[string]interface{}{"note": "it's"}},
+      },
+      "meta": map[string]interface{}{
+        "author": "Dave",
+      },
+    },
+    "footer key": "Copyright",
+  }
+
+  expected := `"footer key" = "Copyright"
+
+[page]
+content = '''
+
+First line
+Second line
+'''
+keywords = ["awesome", "stuff"]
+title = "Today's News"
+
+[[page.links]]
+href = "http://my.domain.com/next.html"
+rel = "next"
+
+[[page.links]]
+rel = "prev"
+
+[page.links.meta]
+note = "it's"
+
+[page.meta]
+author = "Dave"
+`
+
+  var out bytes.Buffer
+  if err := WriteTOML(&out, value); err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+  if out.String() != expected {
+    t.Fatalf("expected:\n%v\ngot:\n%v", expected, out.String())
+  }
+}
+
+func TestWriteTOML_Invalid(t *testing.T) {
+  var out bytes.Buffer
+  if err := WriteTOML(&out, "a"); err == nil {
+    t.Fatalf("expected an error writing a string document")
+  }
+  if err := WriteTOML(&out, map[string]interface{}{"a": nil}); err == nil {
+    t.Fatalf("expected an error writing a null value")
+  }
+}
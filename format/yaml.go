@@ -0,0 +1,190 @@
+package format
+
+import (
+  "io"
+  "bufio"
+  "strings"
+  "github.com/dschnare/doctag/hierarchy"
+)
+
+// WriteYAML writes value as a YAML document.
+// Multi-line strings are written as literal block scalars so that they remain readable,
+// all other strings are written as plain or double quoted scalars.
+func WriteYAML(writer io.Writer, value interface{}) error {
+  buff := bufio.NewWriter(writer)
+  y := &yamlWriter{buff: buff}
+
+  if err := y.writeDocument(value); err != nil {
+    return err
+  }
+
+  return buff.Flush()
+}
+
+type yamlWriter struct {
+  buff *bufio.Writer
+}
+
+func (y *yamlWriter) writeDocument(value interface{}) error {
+  if m,keys,ok := hierarchy.AsMap(value); ok && len(keys) > 0 {
+    return y.writeMap(m, keys, 0, false)
+  }
+  if seq,ok := value.([]interface{}); ok && len(seq) > 0 {
+    return y.writeSlice(seq, 0)
+  }
+
+  // Empty collections and scalars are written on a single line.
+  if err := y.writeInline(value, 0); err != nil {
+    return err
+  }
+  _,err := y.buff.WriteString("\n")
+  return err
+}
+
+// Writes the entries of m, each on its own line at indent.
+// When compact is true the first entry continues the current line (i.e. after a "- ").
+func (y *yamlWriter) writeMap(m map[string]interface{}, keys []string, indent int, compact bool) error {
+  for k,key := range keys {
+    if k > 0 || !compact {
+      y.buff.WriteString(strings.Repeat(" ", indent))
+    }
+    y.buff.WriteString(yamlScalar(key))
+    y.buff.WriteString(":")
+    if err := y.writeValue(m[key], indent); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+// Writes the items of seq, each on its own line at indent.
+func (y *yamlWriter) writeSlice(seq []interface{}, indent int) error {
+  for _,item := range seq {
+    y.buff.WriteString(strings.Repeat(" ", indent))
+    y.buff.WriteString("-")
+    // Maps are written compactly, with their first entry on the same line as the "-".
+    if m,keys,ok := hierarchy.AsMap(item); ok && len(keys) > 0 {
+      y.buff.WriteString(" ")
+      if err := y.writeMap(m, keys, indent + 2, true); err != nil {
+        return err
+      }
+      continue
+    }
+    if err := y.writeValue(item, indent); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+// Writes a value following a "key:" or "-" at indent, including the line break.
+func (y *yamlWriter) writeValue(value interface{}, indent int) error {
+  if m,keys,ok := hierarchy.AsMap(value); ok && len(keys) > 0 {
+    y.buff.WriteString("\n")
+    return y.writeMap(m, keys, indent + 2, false)
+  }
+  if seq,ok := value.([]interface{}); ok && len(seq) > 0 {
+    y.buff.WriteString("\n")
+    return y.writeSlice(seq, indent + 2)
+  }
+
+  y.buff.WriteString(" ")
+  if err := y.writeInline(value, indent); err != nil {
+    return err
+  }
+  _,err := y.buff.WriteString("\n")
+  return err
+}
+
+// Writes a scalar or an empty collection. Block scalars are indented under indent.
+func (y *yamlWriter) writeInline(value interface{}, indent int) error {
+  switch v := value.(type) {
+  case map[string]interface{}, *hierarchy.OrderedMap:
+    _,err := y.buff.WriteString("{}")
+    return err
+  case []interface{}:
+    _,err := y.buff.WriteString("[]")
+    return err
+  case string:
+    if isBlockString(v) {
+      y.writeBlock(v, indent + 2)
+      return nil
+    }
+    _,err := y.buff.WriteString(yamlScalar(v))
+    return err
+  default:
+    text,err := scalarString(v)
+    if err == nil {
+      _,err = y.buff.WriteString(text)
+    }
+    return err
+  }
+}
+
+// Writes str as a literal block scalar with its lines at indent (without the final line break).
+func (y *yamlWriter) writeBlock(str string, indent int) {
+  content := strings.TrimRight(str, "\n")
+  trailing := len(str) - len(content)
+  lines := strings.Split(content, "\n")
+
+  header := "|"
+  // The indentation is detected from the first line with content, unless it's given explicitly.
+  for _,line := range lines {
+    if strings.HasPrefix(line, " ") {
+      header += "2"
+      break
+    }
+    if len(line) > 0 {
+      break
+    }
+  }
+  switch {
+  case trailing == 0:
+    header += "-"
+  case trailing > 1:
+    header += "+"
+  }
+
+  y.buff.WriteString(header)
+  for _,line := range lines {
+    y.buff.WriteString("\n")
+    if len(line) > 0 {
+      y.buff.WriteString(strings.Repeat(" ", indent))
+      y.buff.WriteString(line)
+    }
+  }
+  // The line break of the last line is written by the caller.
+  if trailing > 1 {
+    y.buff.WriteString(strings.Repeat("\n", trailing - 1))
+  }
+}
+
+// Reports whether str can be written as a literal block scalar.
+func isBlockString(str string) bool {
+  if !strings.Contains(str, "\n") || len(strings.TrimSpace(str)) == 0 || !printable(str, "\t\n") {
+    return false
+  }
+  return true
+}
+
+// Returns str as a plain scalar if it can be read back as the same string, otherwise double quoted.
+func yamlScalar(str string) string {
+  if isPlain(str) {
+    return str
+  }
+  return quote(str)
+}
+
+// Reports whether str can be written as a plain YAML scalar that is read back as a string.
+func isPlain(str string) bool {
+  if len(str) == 0 || str != strings.TrimSpace(str) || looksTyped(str) {
+    return false
+  }
+  if strings.ContainsAny(str[:1], "-?:,[]{}#&*!|>'\"%@`") {
+    return false
+  }
+  if strings.Contains(str, ": ") || strings.Contains(str, " #") || strings.HasSuffix(str, ":") {
+    return false
+  }
+  return printable(str, "")
+}
@@ -0,0 +1,92 @@
+package format
+
+import (
+  "testing"
+  "bytes"
+  "github.com/dschnare/doctag/hierarchy"
+)
+
+func TestWriteYAML(t *testing.T) {
+  value := map[string]interface{}{
+    "page": map[string]interface{}{
+      "title": "Today's News",
+      "content": "\nFirst line\n  indented line\n\n",
+      "summary": "One\nTwo",
+      "keywords": []interface{}{"awesome", "true", "1.5"},
+      "links": []interface{}{
+        map[string]interface{}{"rel": "next", "href": "http://my.domain.com/next.html"},
+      },
+      "empty": "",
+    },
+    "key: with colon": "# not a comment",
+  }
+
+  expected := `"key: with colon": "# not a comment"
+page:
+  content: |+
+
+    First line
+      indented line
+
+  empty: ""
+  keywords:
+    - awesome
+    - "true"
+    - "1.5"
+  links:
+    - href: http://my.domain.com/next.html
+      rel: next
+  summary: |-
+    One
+    Two
+  title: Today's News
+`
+
+  var out bytes.Buffer
+  if err := WriteYAML(&out, value); err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+  if out.String() != expected {
+    t.Fatalf("expected:\n%v\ngot:\n%v", expected, out.String())
+  }
+}
+
+func TestWriteYAML_IndentationIndicator(t *testing.T) {
+  var out bytes.Buffer
+  if err := WriteYAML(&out, map[string]interface{}{"a": "  indented\nline\n"}); err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+  if expected := "a: |2\n    indented\n  line\n"; out.String() != expected {
+    t.Fatalf("expected:\n%v\ngot:\n%v", expected, out.String())
+  }
+}
+
+func TestWriteYAML_Ordered(t *testing.T) {
+  page := hierarchy.NewOrderedMap()
+  page.Set("title", "Title")
+  page.Set("content", "Content")
+  value := hierarchy.NewOrderedMap()
+  value.Set("page", page)
+  value.Set("empty", hierarchy.NewOrderedMap())
+  value.Set("footer", "Footer")
+
+  var out bytes.Buffer
+  if err := WriteYAML(&out, value); err != nil {
+    t.Fatal(err)
+  }
+
+  expected := "page:\n  title: Title\n  content: Content\nempty: {}\nfooter: Footer\n"
+  if out.String() != expected {
+    t.Fatalf("expected YAML\n%v\n: got\n%v", expected, out.String())
+  }
+
+  out.Reset()
+  if err := WriteTOML(&out, value); err != nil {
+    t.Fatal(err)
+  }
+
+  expected = "footer = \"Footer\"\n\n[page]\ntitle = \"Title\"\ncontent = \"Content\"\n\n[empty]\n"
+  if out.String() != expected {
+    t.Fatalf("expected TOML\n%v\n: got\n%v", expected, out.String())
+  }
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+  "os"
+  "io"
+  "encoding/json"
+  "github.com/dschnare/doctag/hierarchy"
+  "github.com/dschnare/doctag/writer"
+)
+
+// Reads a JSON object and writes it as a doctag document.
+func doFromJSON() (err error) {
+  var (
+    reader io.Reader
+    object map[string]interface{}
+  )
+
+  if isPiped(os.Stdin) {
+    reader = os.Stdin
+  } else if file,err := os.Open(fileName); err == nil {
+    defer file.Close()
+    reader = file
+  } else {
+    return err
+  }
+
+  if err = json.NewDecoder(reader).Decode(&object); err != nil {
+    return
+  }
+
+  doctags,err := hierarchy.FlattenWithSeparator(object, tagSeparator)
+  if err != nil {
+    return &codeError{code: exitTransform, err: err}
+  }
+
+  w := writer.NewWriter()
+  w.TagPrefix = tagPrefix
+  w.TagSuffix = tagSuffix
+  w.SkipMarker = skipMarker
+  w.Closers = closers
+
+  if noSkip {
+    w.SkipMarker = ""
+  }
+
+  out,err := createWriter()
+  if err != nil {
+    return
+  }
+
+  if err = w.Write(out, doctags); err == nil {
+    err = out.Flush()
+  }
+
+  return
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+  "github.com/dschnare/doctag/generate"
+)
+
+// Parses a doctag file and writes a Go source file for its hierarchy.
+func doGenGo() (err error) {
+  doctags,err := doParse()
+  if err != nil {
+    return
+  }
+
+  object,err := doTransform(doctags)
+  if err != nil {
+    return
+  }
+
+  g := generate.NewGoGenerator()
+  g.Package = goPackage
+  g.TypeName = goType
+  g.VarName = goVar
+
+  out,err := createWriter()
+  if err != nil {
+    return
+  }
+
+  if err = g.Generate(out, object); err == nil {
+    err = out.Flush()
+  }
+
+  return
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+  "testing"
+  "strings"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+)
+
+func TestGenGo_DefaultFlags(t *testing.T) {
+  dir,err := ioutil.TempDir("", "doctag")
+  if err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+  defer os.RemoveAll(dir)
+
+  input := filepath.Join(dir, "content.txt")
+  out := filepath.Join(dir, "content.go")
+  if err = ioutil.WriteFile(input, []byte("<{ page/title }>a<{ page/#keywords }>b<{ page/#keywords }>c"), 0644); err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+
+  parseArgs([]string{"-output=" + out, "gen-go", input})
+  if err = run(); err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+
+  source,err := ioutil.ReadFile(out)
+  if err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+
+  for _,expected := range []string{"Page ContentPage", `Title: "a"`, "Keywords []string", `"b",`, `"c",`} {
+    if !strings.Contains(string(source), expected) {
+      t.Fatalf("expected the generated source to contain '%v' : got\n%v", expected, string(source))
+    }
+  }
+}
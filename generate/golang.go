@@ -0,0 +1,406 @@
+/*
+Package generate generates source code from the results of the hierarchy transformer.
+
+The GoGenerator generates a Go source file with struct types that mirror the
+hierarchy and a variable populated with its values, so that programs get
+compile-time checked content keys.
+
+Example:
+
+Doctag document:
+  <{ page/title }>This is the page title<{!}>
+  <{ page/#keywords }>awesome<{!}>
+  <{ page/#keywords }>stuff<{!}>
+
+Generated source:
+
+  package content
+
+  // Content mirrors the hierarchy of a doctag document.
+  type Content struct {
+    Page ContentPage `json:"page"`
+  }
+
+  // ContentPage mirrors the "page" doctags.
+  type ContentPage struct {
+    Keywords []string `json:"keywords"`
+    Title    string   `json:"title"`
+  }
+
+  // Data holds the values of the doctag document.
+  var Data = Content{
+    Page: ContentPage{
+      Keywords: []string{"awesome", "stuff"},
+      Title:    "This is the page title",
+    },
+  }
+
+Maps become struct types and slices (created by '#' doctags) become slices of
+strings or of struct types. The struct type of a slice item has the fields of
+all items in the slice. Values that cannot be given a single type (e.g. a key
+that is a string in one item and a map in another) are typed interface{}.
+*/
+package generate
+
+import (
+  "io"
+  "fmt"
+  "sort"
+  "bytes"
+  "strconv"
+  "strings"
+  "unicode"
+  "unicode/utf8"
+  "go/format"
+  "github.com/dschnare/doctag/identifier"
+  "github.com/dschnare/doctag/hierarchy"
+)
+
+// The default package, type and variable names used by a GoGenerator.
+const (
+  DefaultGoPackage = "content"
+  DefaultGoType = "Content"
+  DefaultGoVar = "Data"
+)
+
+// A GoGenerator holds the names used when generating Go source files.
+// Use NewGoGenerator to create a GoGenerator with the default names.
+type GoGenerator struct {
+  // Package is the name of the generated package.
+  Package string
+  // TypeName is the name of the struct type of the whole hierarchy.
+  // Nested struct types are named by appending the field names to TypeName.
+  TypeName string
+  // VarName is the name of the variable holding the values. No variable is generated when empty.
+  VarName string
+}
+
+// NewGoGenerator returns a GoGenerator that uses the default names.
+func NewGoGenerator() *GoGenerator {
+  return &GoGenerator{
+    Package: DefaultGoPackage,
+    TypeName: DefaultGoType,
+    VarName: DefaultGoVar,
+  }
+}
+
+// Generate writes a gofmt formatted Go source file for object to writer.
+func (g *GoGenerator) Generate(writer io.Writer, object map[string]interface{}) error {
+  if !isIdentifier(g.Package) || !isExported(g.TypeName) || (len(g.VarName) > 0 && !isExported(g.VarName)) {
+    return fmt.Errorf("The package name must be an identifier, and the type and variable names exported identifiers")
+  }
+
+  root := inferType(object)
+  names := make(map[string]bool)
+  if g.VarName != "" {
+    names[g.VarName] = true
+  }
+  nameTypes(root, g.TypeName, names)
+
+  var src bytes.Buffer
+
+  fmt.Fprintf(&src, "// Code generated by doctag gen-go. DO NOT EDIT.\n\n")
+  fmt.Fprintf(&src, "package %v\n", g.Package)
+
+  for _,t := range structTypes(root, nil) {
+    if t == root {
+      fmt.Fprintf(&src, "\n// %v mirrors the hierarchy of a doctag document.\n", t.name)
+    } else {
+      fmt.Fprintf(&src, "\n// %v mirrors the %q doctags.\n", t.name, t.path)
+    }
+    fmt.Fprintf(&src, "type %v struct {\n", t.name)
+    for _,field := range t.fields {
+      fmt.Fprintf(&src, "%v %v `json:%v`\n", field.name, field.typ.goName(), strconv.Quote(field.key))
+    }
+    fmt.Fprintf(&src, "}\n")
+  }
+
+  if g.VarName != "" {
+    fmt.Fprintf(&src, "\n// %v holds the values of the doctag document.\n", g.VarName)
+    fmt.Fprintf(&src, "var %v = %v\n", g.VarName, literal(root, object, true))
+  }
+
+  formatted,err := format.Source(src.Bytes())
+  if err != nil {
+    return fmt.Errorf("Generated invalid Go source: %v", err.Error())
+  }
+
+  _,err = writer.Write(formatted)
+  return err
+}
+
+// The kinds of Go types generated.
+const (
+  kindAny = iota
+  kindString
+  kindBool
+  kindInt
+  kindFloat
+  kindStruct
+  kindSlice
+)
+
+type goType struct {
+  kind int
+  // The name and hierarchy path of a struct type
+  name string
+  path string
+  fields []*goField
+  // The item type of a slice type
+  elem *goType
+}
+
+type goField struct {
+  key string
+  name string
+  typ *goType
+}
+
+func (t *goType) goName() string {
+  switch t.kind {
+  case kindString:
+    return "string"
+  case kindBool:
+    return "bool"
+  case kindInt:
+    return "int64"
+  case kindFloat:
+    return "float64"
+  case kindStruct:
+    return t.name
+  case kindSlice:
+    return "[]" + t.elem.goName()
+  }
+  return "interface{}"
+}
+
+func (t *goType) field(key string) *goField {
+  for _,field := range t.fields {
+    if field.key == key {
+      return field
+    }
+  }
+  return nil
+}
+
+// Infers the Go type of value.
+func inferType(value interface{}) *goType {
+  switch v := value.(type) {
+  case string:
+    return &goType{kind: kindString}
+  case bool:
+    return &goType{kind: kindBool}
+  case int, int64:
+    return &goType{kind: kindInt}
+  case float64:
+    return &goType{kind: kindFloat}
+  case map[string]interface{}:
+    t := &goType{kind: kindStruct}
+    for _,key := range hierarchy.SortedKeys(v) {
+      t.fields = append(t.fields, &goField{key: key, typ: inferType(v[key])})
+    }
+    return t
+  case []interface{}:
+    var elem *goType
+    for k,item := range v {
+      if k == 0 {
+        elem = inferType(item)
+      } else {
+        elem = unify(elem, inferType(item))
+      }
+    }
+    if elem == nil {
+      elem = &goType{kind: kindAny}
+    }
+    return &goType{kind: kindSlice, elem: elem}
+  }
+  return &goType{kind: kindAny}
+}
+
+// Returns a type that can hold values of both types a and b.
+func unify(a *goType, b *goType) *goType {
+  if a.kind != b.kind {
+    return &goType{kind: kindAny}
+  }
+
+  switch a.kind {
+  case kindStruct:
+    for _,field := range b.fields {
+      if f := a.field(field.key); f != nil {
+        f.typ = unify(f.typ, field.typ)
+      } else {
+        a.fields = append(a.fields, field)
+      }
+    }
+    sort.Slice(a.fields, func (i, j int) bool {
+      return a.fields[i].key < a.fields[j].key
+    })
+  case kindSlice:
+    a.elem = unify(a.elem, b.elem)
+  }
+
+  return a
+}
+
+// Names the struct types and fields below t. Names in use are never reused.
+func nameTypes(t *goType, name string, names map[string]bool) {
+  switch t.kind {
+  case kindSlice:
+    nameTypes(t.elem, name, names)
+  case kindStruct:
+    t.name = uniqueName(name, names)
+    fieldNames := make(map[string]bool)
+    for _,field := range t.fields {
+      field.name = uniqueName(exportedName(field.key), fieldNames)
+      if len(t.path) > 0 {
+        field.typ.path = t.path + "/" + field.key
+      } else {
+        field.typ.path = field.key
+      }
+      if field.typ.kind == kindSlice {
+        field.typ.elem.path = field.typ.path
+      }
+      nameTypes(field.typ, t.name + field.name, names)
+    }
+  }
+}
+
+// Returns the struct types below t (including t) in the order they are declared.
+func structTypes(t *goType, types []*goType) []*goType {
+  switch t.kind {
+  case kindSlice:
+    return structTypes(t.elem, types)
+  case kindStruct:
+    types = append(types, t)
+    for _,field := range t.fields {
+      types = structTypes(field.typ, types)
+    }
+  }
+  return types
+}
+
+// Formats value of type t as a Go literal. The type of struct literals is elided in slice literals.
+func literal(t *goType, value interface{}, typed bool) string {
+  switch t.kind {
+  case kindStruct:
+    m := value.(map[string]interface{})
+    var b strings.Builder
+    if typed {
+      b.WriteString(t.name)
+    }
+    b.WriteString("{\n")
+    for _,field := range t.fields {
+      if v,ok := m[field.key]; ok {
+        fmt.Fprintf(&b, "%v: %v,\n", field.name, literal(field.typ, v, true))
+      }
+    }
+    b.WriteString("}")
+    return b.String()
+  case kindSlice:
+    var b strings.Builder
+    b.WriteString(t.goName() + "{")
+    for _,item := range value.([]interface{}) {
+      fmt.Fprintf(&b, "\n%v,", literal(t.elem, item, false))
+    }
+    if len(value.([]interface{})) > 0 {
+      b.WriteString("\n")
+    }
+    b.WriteString("}")
+    return b.String()
+  case kindAny:
+    return anyLiteral(value)
+  }
+
+  return scalarLiteral(value)
+}
+
+// Formats a value of any type as a literal assignable to interface{}.
+func anyLiteral(value interface{}) string {
+  switch v := value.(type) {
+  case map[string]interface{}:
+    var b strings.Builder
+    b.WriteString("map[string]interface{}{")
+    for _,key := range hierarchy.SortedKeys(v) {
+      fmt.Fprintf(&b, "\n%v: %v,", strconv.Quote(key), anyLiteral(v[key]))
+    }
+    if len(v) > 0 {
+      b.WriteString("\n")
+    }
+    b.WriteString("}")
+    return b.String()
+  case []interface{}:
+    var b strings.Builder
+    b.WriteString("[]interface{}{")
+    for _,item := range v {
+      fmt.Fprintf(&b, "\n%v,", anyLiteral(item))
+    }
+    if len(v) > 0 {
+      b.WriteString("\n")
+    }
+    b.WriteString("}")
+    return b.String()
+  case int, int64:
+    return fmt.Sprintf("int64(%v)", v)
+  case nil:
+    return "nil"
+  }
+  return scalarLiteral(value)
+}
+
+func scalarLiteral(value interface{}) string {
+  switch v := value.(type) {
+  case string:
+    // Multi-line strings are easier to read as raw strings.
+    if strings.Contains(strings.TrimRight(v, "\n"), "\n") && !strings.ContainsAny(v, "`\r") && utf8.ValidString(v) {
+      return "`" + v + "`"
+    }
+    return strconv.Quote(v)
+  case bool:
+    return strconv.FormatBool(v)
+  case int:
+    return strconv.Itoa(v)
+  case int64:
+    return strconv.FormatInt(v, 10)
+  case float64:
+    text := strconv.FormatFloat(v, 'g', -1, 64)
+    if !strings.ContainsAny(text, ".eEnN") {
+      text += ".0"
+    }
+    return text
+  }
+  return "nil"
+}
+
+// Converts a hierarchy key to an exported Go identifier.
+func exportedName(key string) string {
+  name := identifier.ToGoIdentifier(key)
+  if len(name) == 0 {
+    return "Field"
+  }
+
+  r,size := utf8.DecodeRuneInString(name)
+  if upper := unicode.ToUpper(r); unicode.IsUpper(upper) {
+    return string(upper) + name[size:]
+  }
+
+  return "X" + name
+}
+
+// Returns name, or name followed by a number if name is already used, and marks it as used.
+func uniqueName(name string, names map[string]bool) string {
+  unique := name
+  for k := 2; names[unique]; k++ {
+    unique = name + strconv.Itoa(k)
+  }
+  names[unique] = true
+  return unique
+}
+
+func isIdentifier(name string) bool {
+  return len(name) > 0 && identifier.ToGoIdentifier(name) == name
+}
+
+func isExported(name string) bool {
+  r,_ := utf8.DecodeRuneInString(name)
+  return isIdentifier(name) && unicode.IsUpper(r)
+}
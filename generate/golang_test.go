@@ -0,0 +1,184 @@
+package generate
+
+import (
+  "testing"
+  "bytes"
+  "bufio"
+  "strings"
+  "go/ast"
+  "go/token"
+  "go/types"
+  "go/parser"
+  "github.com/dschnare/doctag/parse"
+  "github.com/dschnare/doctag/hierarchy"
+)
+
+// Parses and type checks the generated source.
+func check(t *testing.T, src string) {
+  fset := token.NewFileSet()
+  file,err := parser.ParseFile(fset, "content.go", src, 0)
+  if err != nil {
+    t.Fatalf("expected generated source to parse : got %v", err)
+  }
+  conf := types.Config{}
+  if _,err := conf.Check("content", fset, []*ast.File{file}, nil); err != nil {
+    t.Fatalf("expected generated source to type check : got %v", err)
+  }
+}
+
+func TestGenerate(t *testing.T) {
+  text := `<{ page/title }>This is the page title<{!}>
+<{ page/#keywords }>awesome<{!}>
+<{ page/#keywords }>stuff<{!}>
+<{ page/#links/href }>next.html<{!}>
+<{ page/links/rel }>next<{!}>
+<{ page/#links/href }>prev.html<{!}>
+<{ page/content }>
+First line
+Second line<{!}>`
+
+  doctags,err := parse.Parse(bufio.NewReader(strings.NewReader(text)))
+  if err != nil {
+    t.Fatal(err)
+  }
+  object,err := hierarchy.Transform(doctags, true)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  var out bytes.Buffer
+  if err := NewGoGenerator().Generate(&out, object); err != nil {
+    t.Fatal(err)
+  }
+
+  expected := "// Code generated by doctag gen-go. DO NOT EDIT.\n" +
+  "\n" +
+  "package content\n" +
+  "\n" +
+  "// Content mirrors the hierarchy of a doctag document.\n" +
+  "type Content struct {\n" +
+  "\tPage ContentPage `json:\"page\"`\n" +
+  "}\n" +
+  "\n" +
+  "// ContentPage mirrors the \"page\" doctags.\n" +
+  "type ContentPage struct {\n" +
+  "\tContent  string             `json:\"content\"`\n" +
+  "\tKeywords []string           `json:\"keywords\"`\n" +
+  "\tLinks    []ContentPageLinks `json:\"links\"`\n" +
+  "\tTitle    string             `json:\"title\"`\n" +
+  "}\n" +
+  "\n" +
+  "// ContentPageLinks mirrors the \"page/links\" doctags.\n" +
+  "type ContentPageLinks struct {\n" +
+  "\tHref string `json:\"href\"`\n" +
+  "\tRel  string `json:\"rel\"`\n" +
+  "}\n" +
+  "\n" +
+  "// Data holds the values of the doctag document.\n" +
+  "var Data = Content{\n" +
+  "\tPage: ContentPage{\n" +
+  "\t\tContent: `\n" +
+  "First line\n" +
+  "Second line`,\n" +
+  "\t\tKeywords: []string{\n" +
+  "\t\t\t\"awesome\",\n" +
+  "\t\t\t\"stuff\",\n" +
+  "\t\t},\n" +
+  "\t\tLinks: []ContentPageLinks{\n" +
+  "\t\t\t{\n" +
+  "\t\t\t\tHref: \"next.html\",\n" +
+  "\t\t\t\tRel:  \"next\",\n" +
+  "\t\t\t},\n" +
+  "\t\t\t{\n" +
+  "\t\t\t\tHref: \"prev.html\",\n" +
+  "\t\t\t},\n" +
+  "\t\t},\n" +
+  "\t\tTitle: \"This is the page title\",\n" +
+  "\t},\n" +
+  "}\n"
+
+  if out.String() != expected {
+    t.Fatalf("expected generated source to be\n%v\n: got\n%v", expected, out.String())
+  }
+  check(t, out.String())
+}
+
+func TestGenerateTypes(t *testing.T) {
+  object := map[string]interface{}{
+    "mixed": []interface{}{"text", map[string]interface{}{"a": "b"}},
+    "items": []interface{}{
+      map[string]interface{}{"value": "text", "count": int64(2)},
+      map[string]interface{}{"value": map[string]interface{}{"a": "b"}, "ratio": 1.0, "ok": true},
+    },
+    "empty": []interface{}{},
+    "Data": "collides with the variable",
+    "a-b": "collides with ab",
+    "ab": "collides with a-b",
+    "_private": "not exported",
+    "nothing": nil,
+  }
+
+  g := NewGoGenerator()
+  g.Package = "gen"
+  g.TypeName = "Doc"
+
+  var out bytes.Buffer
+  if err := g.Generate(&out, object); err != nil {
+    t.Fatal(err)
+  }
+  src := out.String()
+
+  // Ignore the alignment of fields.
+  compact := strings.Join(strings.Fields(src), " ")
+
+  for _,expected := range []string{
+    "package gen",
+    "type Doc struct {",
+    "Ab string `json:\"a-b\"`",
+    "Ab2 string `json:\"ab\"`",
+    "Data string `json:\"Data\"`",
+    "Empty []interface{} `json:\"empty\"`",
+    "Items []DocItems `json:\"items\"`",
+    "Mixed []interface{} `json:\"mixed\"`",
+    "Nothing interface{} `json:\"nothing\"`",
+    "X_private string `json:\"_private\"`",
+    "Count int64 `json:\"count\"`",
+    "Ok bool `json:\"ok\"`",
+    "Ratio float64 `json:\"ratio\"`",
+    "Value interface{} `json:\"value\"`",
+    "Value: map[string]interface{}{ \"a\": \"b\", },",
+    "Ratio: 1.0,",
+    "var Data = Doc{",
+  } {
+    if !strings.Contains(compact, expected) {
+      t.Fatalf("expected generated source to contain %q : got\n%v", expected, src)
+    }
+  }
+  check(t, src)
+}
+
+func TestGenerateNames(t *testing.T) {
+  object := map[string]interface{}{"a": "b"}
+
+  for _,g := range []*GoGenerator{
+    &GoGenerator{Package: "", TypeName: "Content"},
+    &GoGenerator{Package: "content", TypeName: "content"},
+    &GoGenerator{Package: "content", TypeName: "Content", VarName: "my data"},
+  } {
+    var out bytes.Buffer
+    if err := g.Generate(&out, object); err == nil {
+      t.Fatalf("expected an error for the names %q, %q and %q : got nil", g.Package, g.TypeName, g.VarName)
+    }
+  }
+
+  g := NewGoGenerator()
+  g.VarName = ""
+  var out bytes.Buffer
+  if err := g.Generate(&out, object); err != nil {
+    t.Fatal(err)
+  }
+  if strings.Contains(out.String(), "var ") {
+    t.Fatalf("expected no variable : got\n%v", out.String())
+  }
+  check(t, out.String())
+}
@@ -0,0 +1,139 @@
+package hierarchy
+
+import (
+  "fmt"
+  "strconv"
+  "strings"
+  "unicode"
+  "github.com/dschnare/doctag/parse"
+)
+
+// Flatten flattens a hierarchical map into a slice of DoctagNodes, the inverse of Transform.
+// The default separator character will be used to join the path names of the doctags.
+func Flatten(object interface{}) ([]*parse.DoctagNode, error) {
+  return FlattenWithSeparator(object, DefaultSeparator)
+}
+
+// FlattenWithSeparator flattens a hierarchical map into a slice of DoctagNodes
+// whose names are paths joined with a specific separator character.
+//
+// The object must be a map[string]interface{} (such as one decoded by encoding/json
+// or returned by Transform) or an *OrderedMap (such as one returned by TransformOrdered).
+// Maps are flattened into paths, and slices are flattened by prefixing the key of the
+// slice with '#' in the first doctag of each item. Map keys are visited in sorted order
+// and the keys of OrderedMaps in order.
+//
+// Strings are used as values as-is, while numbers, booleans and nil are converted
+// to strings. Transform(Flatten(object)) reproduces the object when all of its values
+// are strings. An error is returned for objects that cannot be represented by doctags,
+// such as empty maps or slices nested in the object, slices of slices and keys that
+// are empty, start with '#', contain whitespace or the separator character or end
+// with an index (e.g. "links[1]" or "[1]"), which Transform would read as a slice index.
+func FlattenWithSeparator(object interface{}, separator rune) ([]*parse.DoctagNode, error) {
+  m,keys,ok := AsMap(object)
+  if !ok {
+    return nil,fmt.Errorf("Cannot flatten a %T, expected a map", object)
+  }
+
+  f := &flattener{separator: string(separator), doctags: make([]*parse.DoctagNode, 0, 50)}
+
+  for _,key := range keys {
+    if err := f.flatten(m[key], nil, key, false); err != nil {
+      return nil,err
+    }
+  }
+
+  return f.doctags,nil
+}
+
+type flattener struct {
+  separator string
+  doctags []*parse.DoctagNode
+}
+
+// A path name of a doctag being flattened.
+// An appending segment is prefixed with '#' until the first doctag below it is created.
+type segment struct {
+  name string
+  appending bool
+}
+
+// Flattens value found at key under the path.
+// When appending is true the first doctag created for value will append to the slice at key.
+func (f *flattener) flatten(value interface{}, path []*segment, key string, appending bool) error {
+  if len(key) == 0 || strings.HasPrefix(key, "#") || strings.Contains(key, f.separator) || strings.IndexFunc(key, unicode.IsSpace) >= 0 {
+    return fmt.Errorf("Path %q cannot be empty, start with '#' or contain whitespace or '%v'", f.join(path, key), f.separator)
+  }
+  if hasIndex(key) || isIndex(key) {
+    return fmt.Errorf("Path %q cannot end with an index such as '[1]'", f.join(path, key))
+  }
+
+  // Copy the path so that siblings never share segments.
+  path = append(path[:len(path):len(path)], &segment{name: key, appending: appending})
+
+  if m,keys,ok := AsMap(value); ok {
+    if len(keys) == 0 {
+      return fmt.Errorf("Path %q cannot be an empty map", f.join(path[:len(path) - 1], key))
+    }
+    for _,name := range keys {
+      if err := f.flatten(m[name], path, name, false); err != nil {
+        return err
+      }
+    }
+    return nil
+  }
+
+  switch value.(type) {
+  case []interface{}:
+    seq := value.([]interface{})
+    if appending {
+      return fmt.Errorf("Path %q cannot be a slice of slices", f.join(path[:len(path) - 1], key))
+    }
+    if len(seq) == 0 {
+      return fmt.Errorf("Path %q cannot be an empty slice", f.join(path[:len(path) - 1], key))
+    }
+    for _,item := range seq {
+      if err := f.flatten(item, path[:len(path) - 1], key, true); err != nil {
+        return err
+      }
+    }
+  default:
+    names := make([]string, len(path))
+    for k,seg := range path {
+      names[k] = seg.name
+      if seg.appending {
+        // Only the first doctag appends, the others resolve to the appended item.
+        names[k] = "#" + seg.name
+        seg.appending = false
+      }
+    }
+    f.doctags = append(f.doctags, &parse.DoctagNode{
+      Name: strings.Join(names, f.separator),
+      Value: toString(value),
+    })
+  }
+
+  return nil
+}
+
+// Joins the path and key for error messages.
+func (f *flattener) join(path []*segment, key string) string {
+  names := make([]string, 0, len(path) + 1)
+  for _,seg := range path {
+    names = append(names, seg.name)
+  }
+  return strings.Join(append(names, key), f.separator)
+}
+
+// Converts a leaf value to a string.
+func toString(value interface{}) string {
+  switch v := value.(type) {
+  case nil:
+    return ""
+  case string:
+    return v
+  case float64:
+    return strconv.FormatFloat(v, 'f', -1, 64)
+  }
+  return fmt.Sprint(value)
+}
@@ -0,0 +1,115 @@
+package hierarchy
+
+import (
+  "testing"
+  "io/ioutil"
+  "encoding/json"
+  "github.com/dschnare/doctag/parse"
+)
+
+func TestFlatten_RoundTrip(t *testing.T) {
+  b,err := ioutil.ReadFile("./fixtures/cms.json")
+  if err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+
+  var object map[string]interface{}
+  if err = json.Unmarshal(b, &object); err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+
+  doctags,err := Flatten(object)
+  if err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+
+  if doctags[0].Name != "footer" || doctags[1].Name != "page/#keywords" || doctags[3].Name != "page/#keywords" {
+    t.Fatalf("expected doctags in key order : got %v, %v, %v", doctags[0].Name, doctags[1].Name, doctags[3].Name)
+  }
+
+  result,err := Transform(doctags, false)
+  if err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+
+  expected,_ := json.Marshal(object)
+  actual,_ := json.Marshal(result)
+
+  if string(expected) != string(actual) {
+    t.Fatalf("expected %s : got %s", expected, actual)
+  }
+}
+
+func TestFlatten_Ordered(t *testing.T) {
+  doctags := []*parse.DoctagNode{
+    &parse.DoctagNode{Name: "page/title", Value: "Title"},
+    &parse.DoctagNode{Name: "page/#links/rel", Value: "next"},
+    &parse.DoctagNode{Name: "page/links/href", Value: "next.html"},
+    &parse.DoctagNode{Name: "footer", Value: "Footer"},
+  }
+
+  ordered,err := NewTransformer().TransformOrdered(doctags)
+  if err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+
+  // Nested OrderedMaps are flattened in order too.
+  flattened,err := Flatten(ordered)
+  if err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+  if len(flattened) != len(doctags) {
+    t.Fatalf("expected %v doctags : got %v", len(doctags), len(flattened))
+  }
+  for k,doctag := range doctags {
+    if flattened[k].Name != doctag.Name || flattened[k].Value != doctag.Value {
+      t.Fatalf("expected doctag %v to be %v : got %v", k, doctag.Name, flattened[k].Name)
+    }
+  }
+
+  if _,err := Flatten(map[string]interface{}{"a": NewOrderedMap()}); err == nil {
+    t.Fatalf("expected an error flattening an empty ordered map")
+  }
+}
+
+func TestFlatten_Invalid(t *testing.T) {
+  invalid := []map[string]interface{}{
+    {"a": map[string]interface{}{}},
+    {"a": []interface{}{}},
+    {"a": []interface{}{[]interface{}{"b"}}},
+    {"#a": "b"},
+    {"a b": "c"},
+    {"a/b": "c"},
+    {"": "c"},
+    {"links[1]": "x"},
+    {"[1]": "x"},
+    {"a": map[string]interface{}{"tags[0]": "y"}},
+  }
+
+  for _,object := range invalid {
+    if _,err := Flatten(object); err == nil {
+      t.Fatalf("expected an error flattening %v", object)
+    }
+  }
+}
+
+func TestFlatten_RoundTripBrackets(t *testing.T) {
+  object := map[string]interface{}{
+    "a[b]": "x",
+    "[a]": "y",
+    "c[1]d": "z",
+    "e[]": "w",
+  }
+
+  doctags,err := Flatten(object)
+  if err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+
+  result,err := Transform(doctags, false)
+  if err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+
+  testValue(result, object, t)
+}
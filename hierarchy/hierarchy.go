@@ -69,20 +69,133 @@ Map hierarchy:
         ],
      },
   }
+
+A path name followed by '[N]' refers to the item at the zero-based index N of
+the slice indexed by the path name, so that items can be set out of order. Items
+missing before the index are created as empty maps (or empty strings when the
+doctag sets the item itself). Path names of digits prefixed with '#' (e.g. '#2')
+still append to a slice indexed by the digits, they are never indices.
+
+Example:
+
+Doctag document:
+  <{ page/links[1]/rel }>next<{!}>
+  <{ page/links[0]/rel }>alternate<{!}>
+  <{ page/links[1]/href }>http://my.domain.com/next.html<{!}>
+
+Map hierarchy:
+
+  map{
+     "page": map{
+        "links": [
+          map{
+            "rel": "alternate",
+          },
+          map{
+            "rel": "next",
+            "href": "http://my.domain.com/next.html",
+          },
+        ],
+     },
+  }
+
+A path that is used both as a value and as a map (e.g. <{ a }>x followed by
+<{ a/b }>y) is a conflict. By default a map (or slice) replaces the value, and a
+later value is ignored when the path is already a map (or slice). The Conflicts
+field of a Transformer can instead keep the last or the first, report a warning
+or return an error with the positions of both doctags.
+
+A path that is assigned a value more than once is a duplicate. By default the
+last value is kept, the Duplicates field of a Transformer can instead keep the
+first, report a warning or an error, concatenate the values or promote them to
+a slice. Doctags prefixed with '#' and explicit indices are never duplicates of
+each other, except when an index refers to an item already set by a doctag.
+
+Values are strings unless a Transformer is configured to convert them. With
+TypesAnnotated, the "type" attribute of a doctag converts its value to an int,
+float, bool, null or an embedded JSON value. TypesInferred also converts values
+that look like numbers, booleans or null.
+
+Example:
+
+Doctag document:
+  <{ page/count type=int }>42<{!}>
+  <{ page/draft type=bool }>true<{!}>
+  <{ page/meta type=json }>{"tags": ["a", "b"]}<{!}>
+
+Map hierarchy:
+
+  map{
+     "page": map{
+        "count": int64(42),
+        "draft": true,
+        "meta": map{
+          "tags": ["a", "b"],
+        },
+     },
+  }
+
+The slices in the results are []interface{}, like the ones decoded by encoding/json.
+
+Transform returns Go maps, whose keys have no order. TransformOrdered returns
+OrderedMaps instead, with keys in the order they first appear in the doctags,
+which are encoded to JSON (and written by the format package) in that order.
+
+TransformTree returns a tree of Nodes, where every value, map and slice remembers
+the doctags that produced it. Problems found in the hierarchy can then be
+reported at the line and column of the doctag, for example:
+
+  root,err := transformer.TransformTree(doctags)
+  ...
+  if title := root.Find("page/title", '/'); title != nil && len(title.Value.(string)) > 80 {
+    doctag := title.Doctag()
+    fmt.Printf("%v:%v: page/title too long\n", doctag.Line, doctag.Column)
+  }
+
+Merge layers trees, e.g. base content followed by its overrides: maps are merged
+deeply, values are replaced by later layers and slices are replaced or appended to.
+The doctags of the merged nodes tell which file each final value came from.
+
+Flatten performs the reverse transformation, turning a map hierarchy (for example
+one decoded from JSON, or the OrderedMap returned by TransformOrdered) into a slice
+of doctags.
 */
 package hierarchy
 
 import (
   "fmt"
+  "strconv"
   "strings"
   "unicode"
   "github.com/dschnare/doctag/parse"
-  "github.com/dschnare/doctag/identifier"
 )
 
 // DefaultSeparator is a constant for the default character used to delimit separate doctag names.
 const DefaultSeparator = '/'
 
+// The codes of the diagnostics returned by the transform functions.
+// Errors returned by the transform functions are *parse.Diagnostic values.
+const (
+  // CodeInvalidPath reports a doctag path segment equal to "#".
+  CodeInvalidPath parse.Code = "invalid-path"
+  // CodeEmptyPath reports a doctag path segment that is empty after converting to an identifier.
+  CodeEmptyPath parse.Code = "empty-path"
+  // CodeInvalidIndex reports an index segment that does not follow a path name
+  // of a slice or that is greater than MaxIndex.
+  CodeInvalidIndex parse.Code = "invalid-index"
+  // CodeInvalidType reports a type attribute with an unknown type.
+  CodeInvalidType parse.Code = "invalid-type"
+  // CodeInvalidValue reports a value that cannot be converted to its annotated type.
+  CodeInvalidValue parse.Code = "invalid-value"
+  // CodeConflict reports a path used both as a map (or slice) and as a value.
+  CodeConflict parse.Code = "conflict"
+  // CodeDuplicate reports a path that is assigned a value more than once.
+  CodeDuplicate parse.Code = "duplicate"
+)
+
+// MaxIndex is the greatest index that can be used in a path segment.
+const MaxIndex = 1 << 16
+
 // Transform transforms a slice of DoctagNodes into a hierarchical map that represents a JSON object.
 // The default separater character will be used when parsing hierarchical doctags.
 func Transform(doctags []*parse.DoctagNode, jsonKeysToIdentifiers bool) (map[string]interface{}, error) {
@@ -91,38 +204,28 @@ func Transform(doctags []*parse.DoctagNode, jsonKeysToIdentifiers bool) (map[str
 
 // TransformWithSeparator transforms a slice of DoctagNodes with a specific doctag separator character into a hierarchical map that represents a JSON object.
 func TransformWithSeparator(doctags []*parse.DoctagNode, jsonKeysToIdentifiers bool, separator rune) (map[string]interface{}, error) {
-  var err error
-  object := make(map[string]interface{})
-
-  for _,doctag := range doctags {
-    pathNames := getPathNames(doctag.Name, separator)
-    last := len(pathNames) - 1
-    var o interface{} = object
-
-    for g,pathName := range pathNames {
-      if pathName == "#" {
-        return nil,fmt.Errorf("Line: %v, Column: %v :: Path cannot equal '#'", doctag.Line, doctag.Column)
-        break
-      }
-      if jsonKeysToIdentifiers {
-        // When we convert to an identifier we prserve the "#" prefix.
-        // The prefix is trimmed when actually saving to the map.
-        pathName = identifier.ToIdentifierFunc(pathName, identifierValidRuneFunc)
-        if len(pathName) == 0 {
-          return nil,fmt.Errorf("Line: %v, Column: %v :: After converting to an identifier, path is empty", doctag.Line, doctag.Column)
-          break
-        }
-      }
-      if g == last {
-        // setKey(o, pathName, doctag.Value)
-        resolveWithValue(o, pathName, doctag.Value)
-      } else {
-        o = resolve(o, pathName)
-      }
-    }
-  }
+  t := NewTransformer()
+  t.KeysToIdentifiers = jsonKeysToIdentifiers
+  t.Separator = separator
+  return t.Transform(doctags)
+}
 
-  return object,err
+// Creates an error diagnostic located at doctag.
+func nodeError(doctag *parse.DoctagNode, code parse.Code, message string) error {
+  return nodeDiagnostic(doctag, parse.SeverityError, code, message)
+}
+
+// Creates a diagnostic located at doctag.
+func nodeDiagnostic(doctag *parse.DoctagNode, severity parse.Severity, code parse.Code, message string) *parse.Diagnostic {
+  return &parse.Diagnostic{
+    Code: code,
+    Severity: severity,
+    File: doctag.File,
+    Line: doctag.Line,
+    Column: doctag.Column,
+    Offset: doctag.Tag.Start.Offset,
+    Message: message,
+  }
 }
 
 // Preseve the "#" prefix, otherwise same as ToGoIdentifier().
@@ -136,112 +239,349 @@ func identifierValidRuneFunc(r rune, idLen int) bool {
 }
 
 // Takes a hierarchical doctag name and splits it into separate path names.
+// Indices following a path name (e.g. "links[2]") are split into index segments (e.g. "links", "[2]").
 func getPathNames(tagName string, separator rune) []string {
-  return strings.FieldsFunc(tagName, func (r rune) bool {
+  fields := strings.FieldsFunc(tagName, func (r rune) bool {
     return unicode.IsSpace(r) || r == separator
   })
+  pathNames := make([]string, 0, len(fields))
+
+  for _,field := range fields {
+    var indices []string
+    for hasIndex(field) {
+      open := strings.LastIndex(field, "[")
+      indices = append([]string{field[open:]}, indices...)
+      field = field[:open]
+    }
+    pathNames = append(pathNames, field)
+    pathNames = append(pathNames, indices...)
+  }
+
+  return pathNames
+}
+
+// Reports whether a path name ends with an index following a name, e.g. "links[2]".
+func hasIndex(pathName string) bool {
+  open := strings.LastIndex(pathName, "[")
+  return open > 0 && isIndex(pathName[open:])
+}
+
+// Reports whether a path name is an index segment, i.e. digits between '[' and ']'.
+func isIndex(pathName string) bool {
+  if len(pathName) < 3 || pathName[0] != '[' || pathName[len(pathName) - 1] != ']' {
+    return false
+  }
+  for _,r := range pathName[1:len(pathName) - 1] {
+    if r < '0' || r > '9' {
+      return false
+    }
+  }
+  return true
+}
+
+// Returns the index segment of an index, e.g. "[2]".
+func indexName(index int) string {
+  return "[" + strconv.Itoa(index) + "]"
+}
+
+// The state of a single transformation by a Transformer.
+type transform struct {
+  *Transformer
+  // The doctag being transformed.
+  doctag *parse.DoctagNode
 }
 
-// Resolve a key on the specified object o.
-func resolve(o interface{}, key string) (interface{}) {
-  value := make(map[string]interface{})
-  return resolveWithValue(o, key, value)
+// Records that the doctag being transformed produced (or added to) the node n.
+func (t *transform) touch(n *Node) *Node {
+  if len(n.Doctags) == 0 || n.Doctags[len(n.Doctags) - 1] != t.doctag {
+    n.Doctags = append(n.Doctags, t.doctag)
+  }
+  return n
+}
+
+// Joins a path and a key. The items of lists have paths ending with their index (e.g. "page/links[2]").
+func (t *transform) join(path string, key string) string {
+  if len(path) == 0 || isIndex(key) {
+    return path + key
+  }
+  return path + string(t.Separator) + key
 }
-func resolveWithValue(o interface{}, key string, value interface{}) (interface{}) {
-  if value == nil {
-    value = make(map[string]interface{})
+
+// Resolve a key on the map n to a map, creating the map if required. Keys on a list are resolved on
+// its last item. Keys prefixed with "#" append a new map to a list. Keys that refer to a list resolve to the list.
+// Returns the resolved node and its path, or nil when the doctag is ignored because of a conflict.
+func (t *transform) resolve(n *Node, path string, key string) (*Node, string, error) {
+  if n.Kind == ListNode {
+    item,itemPath,err := t.lastItem(n, path)
+    if item == nil {
+      return nil,"",err
+    }
+    return t.resolve(item, itemPath, key)
+  }
+
+  // The key must refer to a list with a new map appended.
+  if strings.HasPrefix(key, "#") {
+    child := t.touch(newMap())
+    return child,t.append(n, path, key[1:], child),nil
   }
 
-  switch o.(type) {
-  case map[string]interface{}:
-    m := o.(map[string]interface{})
-    return resolveFromMapWithValue(m, key, value)
-  case *[]interface{}:
-    seqPtr := o.(*[]interface{})
-    return resolveFromSliceWithValue(seqPtr, key, value)
+  keyPath := t.join(path, key)
+  child := n.Children[key]
+
+  // If the key exists and it's a string (or another typed value) then it conflicts with the map.
+  if child != nil && child.Kind == LeafNode {
+    if replace,err := t.conflict(child, keyPath, MapNode); !replace {
+      return nil,"",err
+    }
+    child = nil
+  }
+  // If the key does not exist then we create a map and set the key.
+  if child == nil {
+    child = newMap()
+    n.set(key, child)
   }
 
-  return o
+  return t.touch(child),keyPath,nil
 }
 
-func resolveFromMapWithValue(m map[string]interface{}, key string, value interface{}) (interface{}) {
-  // The key must refer refer to a slice with a new map appended.
+// Assign a value to a key on the map n (or the last item of the list n). Keys prefixed with "#" append the value to a list.
+func (t *transform) assign(n *Node, path string, key string, value *Node) (err error) {
+  if n.Kind == ListNode {
+    item,itemPath,err := t.lastItem(n, path)
+    if item == nil {
+      return err
+    }
+    return t.assign(item, itemPath, key, value)
+  }
+
   if strings.HasPrefix(key, "#") {
-    key = key[1:]
-    // If the key exists then we ensure it refers to a slice, replacing the key if required.
-    if v,ok := m[key]; ok {
-      if seqPtr,ok := v.(*[]interface{}); ok {
-        seq := *seqPtr
-        seq = append(seq, value)
-        *seqPtr = seq
-        return value
-      } else {
-        temp := make([]interface{}, 2, 50)
-        temp[0] = v
-        temp[1] = value
-        m[key] = &temp
-        return value
+    t.append(n, path, key[1:], value)
+    return nil
+  }
+
+  keyPath := t.join(path, key)
+
+  // An existing value is a duplicate, but an existing map or list conflicts with the value.
+  if child := n.Children[key]; child != nil {
+    if child.promoted {
+      // Further duplicates are appended to a list that duplicates were promoted to.
+      t.touch(child).Items = append(child.Items, value)
+      return nil
+    } else if child.Kind == LeafNode && value.Kind == LeafNode {
+      var replace bool
+      if value,replace,err = t.duplicate(child, keyPath, value); !replace {
+        return err
       }
-    // If the key does not exist then we create a slice and set the key.
-    } else {
-      temp := make([]interface{}, 1, 50)
-      temp[0] = value
-      m[key] = &temp
-      return value
+    } else if replace,err := t.conflict(child, keyPath, value.Kind); !replace {
+      return err
     }
-  // The key must refer to a map.
-  } else {
-    // If the key does not exist then we create a map and set the key.
-    if v,ok := m[key]; !ok {
-      m[key] = value
-    // If the key exists and it's a string then we create a map and set the key.
-    } else if _,ok := v.(string); ok {
-      m[key] = value
+  }
+
+  n.set(key, value)
+  return nil
+}
+
+// Appends value to the list at key on the map n, creating the list if required. Returns the path of the appended item.
+func (t *transform) append(n *Node, path string, key string, value *Node) string {
+  keyPath := t.join(path, key)
+  list := n.Children[key]
+
+  if list == nil {
+    list = newList()
+    n.set(key, list)
+  } else if list.Kind != ListNode {
+    // An existing value (or map) becomes the first item of the list.
+    list = promote(list)
+    n.set(key, list)
+  }
+
+  t.touch(list).Items = append(list.Items, value)
+  return t.join(keyPath, indexName(len(list.Items) - 1))
+}
+
+// Returns the last item of a list, which keys are resolved on, and its path.
+// A map is appended to an empty list. Returns nil when the doctag is ignored because of a conflict.
+func (t *transform) lastItem(list *Node, path string) (*Node, string, error) {
+  if len(list.Items) == 0 {
+    list.Items = append(list.Items, newMap())
+  }
+
+  index := len(list.Items) - 1
+  itemPath := t.join(path, indexName(index))
+  item := list.Items[index]
+
+  if item.Kind == LeafNode {
+    if replace,err := t.conflict(item, itemPath, MapNode); !replace {
+      return nil,"",err
+    }
+    item = newMap()
+    list.Items[index] = item
+  }
+
+  return t.touch(item),itemPath,nil
+}
+
+// Resolve a key on the map n (or the last item of the list n) to a list, creating the list if required.
+// Returns nil when the doctag is ignored because of a conflict.
+func (t *transform) resolveList(n *Node, path string, key string) (*Node, string, error) {
+  if n.Kind == ListNode {
+    item,itemPath,err := t.lastItem(n, path)
+    if item == nil {
+      return nil,"",err
     }
+    return t.resolveList(item, itemPath, key)
   }
 
-  // Return the value referred to by the key.
-  return m[key]
-}
-
-func resolveFromSliceWithValue(seqPtr *[]interface{}, key string, value interface{}) (interface{}) {
-  seq := *seqPtr
-
-  // Sequences (i.e. slices) are treated like the following:
-  // - if the key is prefixed with "#" then the key must
-  //    refer to another slice on the last map in the sequence.
-  // - if the key is not prefixed with "#" then the key must
-  //    refer to a map of on the last map in the sequence.
-
-  // If the sequence has not items.
-  if len(seq) == 0 {
-    // The key must refer to a slice with a new map appended.
-    if strings.HasPrefix(key, "#") {
-      key = key[1:]
-      // Create a map (i.e. the new last item in the sequence) and a slice,
-      // set the key on the map to the slice and append the map to the sequence.
-      obj := make(map[string]interface{})
-      temp := make([]interface{}, 1, 50)
-      temp[0] = value
-      obj[key] = &temp
-      seq = append(seq, obj)
-      *seqPtr = seq
-      return value
-    // The key must refer to a map.
+  keyPath := t.join(path, key)
+  child := n.Children[key]
+
+  if child == nil {
+    child = newList()
+    n.set(key, child)
+  } else if child.Kind == MapNode {
+    return nil,"",nodeError(t.doctag, CodeInvalidIndex, fmt.Sprintf("Path '%v' is not a slice and cannot be indexed", keyPath))
+  } else if child.Kind == LeafNode {
+    // Same as appending to a string, the string (or other typed value) becomes the first item.
+    child = promote(child)
+    n.set(key, child)
+  }
+
+  return t.touch(child),keyPath,nil
+}
+
+// Resolve the item at index in a list, growing the list if required. Items missing before
+// the index are created as empty maps (or empty strings when last is true).
+// When last is true the item is set to value, otherwise the item is resolved to a map.
+// Returns nil when the doctag is ignored because of a conflict.
+func (t *transform) resolveIndex(list *Node, path string, index int, value *Node, last bool) (*Node, string, error) {
+  for len(list.Items) <= index {
+    if last {
+      list.Items = append(list.Items, newLeaf(""))
     } else {
-      // Create a map (i.e. the new last item in the sequence) set the key
-      // to another newly created map and append the first map to the sequence.
-      obj := make(map[string]interface{})
-      obj[key] = value
-      seq = append(seq, obj)
-      *seqPtr = seq
-      // Return the second newly created map.
-      return value
+      list.Items = append(list.Items, newMap())
     }
-  // If the sequence has items then we grab the last item and recursively resolve.
-  // We can recursivly call resolve() because we'll never have slices of slices of slices ...
-  } else {
-    lastItem := seq[len(seq) - 1]
-    return resolveWithValue(lastItem, key, value)
   }
-}
\ No newline at end of file
+
+  itemPath := t.join(path, indexName(index))
+  item := list.Items[index]
+  // Items created to fill the list were not produced by a doctag and never conflict.
+  set := len(item.Doctags) > 0
+
+  if last {
+    if item.promoted {
+      t.touch(item).Items = append(item.Items, value)
+      return value,itemPath,nil
+    } else if set && item.Kind == LeafNode && value.Kind == LeafNode {
+      var (
+        replace bool
+        err error
+      )
+      if value,replace,err = t.duplicate(item, itemPath, value); !replace {
+        return nil,"",err
+      }
+    } else if set {
+      if replace,err := t.conflict(item, itemPath, value.Kind); !replace {
+        return nil,"",err
+      }
+    }
+    list.Items[index] = value
+    return value,itemPath,nil
+  }
+
+  if item.Kind != LeafNode {
+    return t.touch(item),itemPath,nil
+  }
+
+  if set {
+    if replace,err := t.conflict(item, itemPath, MapNode); !replace {
+      return nil,"",err
+    }
+  }
+  item = newMap()
+  list.Items[index] = item
+
+  return t.touch(item),itemPath,nil
+}
+
+// Handles the doctag being transformed using the path of an existing node as a different kind of node
+// (i.e. a map or list as a value and vice versa), according to the conflict policy.
+// Reports whether the doctag replaces the existing node, otherwise the doctag is ignored.
+func (t *transform) conflict(existing *Node, path string, kind NodeKind) (bool, error) {
+  message := fmt.Sprintf("Path '%v' cannot be %v, it's already %v", path, kind, existing.Kind) + t.origin(existing)
+
+  switch t.Conflicts {
+  case ConflictError:
+    return false,nodeError(t.doctag, CodeConflict, message)
+  case ConflictWarn:
+    t.warn(CodeConflict, message)
+  case ConflictFirstWins:
+    return false,nil
+  case ConflictMapsWin:
+    return existing.Kind == LeafNode,nil
+  }
+
+  return true,nil
+}
+
+// Handles the doctag being transformed assigning value to the path of an existing leaf,
+// according to the duplicate policy. Returns the node to set at the path and whether
+// the existing leaf is replaced, otherwise the doctag is ignored.
+func (t *transform) duplicate(existing *Node, path string, value *Node) (*Node, bool, error) {
+  message := fmt.Sprintf("Path '%v' already has a value", path) + t.origin(existing)
+
+  switch t.Duplicates {
+  case DuplicateError:
+    return nil,false,nodeError(t.doctag, CodeDuplicate, message)
+  case DuplicateWarn:
+    t.warn(CodeDuplicate, message)
+  case DuplicateFirstWins:
+    return nil,false,nil
+  case DuplicateConcat:
+    existing.Value = toString(existing.Value) + t.DuplicateSeparator + toString(value.Value)
+    return t.touch(existing),true,nil
+  case DuplicateSlice:
+    list := promote(existing)
+    list.promoted = true
+    t.touch(list).Items = append(list.Items, value)
+    return list,true,nil
+  }
+
+  return value,true,nil
+}
+
+// Reports a warning located at the doctag being transformed.
+func (t *transform) warn(code parse.Code, message string) {
+  if t.Logger == nil && t.WarningHandler == nil {
+    return
+  }
+
+  warning := nodeDiagnostic(t.doctag, parse.SeverityWarning, code, message)
+
+  if t.Logger != nil {
+    t.Logger.Printf("\nLine: %v, Column: %v\n%v\n\n", warning.Line, warning.Column, warning.Message)
+  }
+  if t.WarningHandler != nil {
+    t.WarningHandler(warning)
+  }
+}
+
+// Returns a list with n as its first item, created by the doctag that created n.
+func promote(n *Node) *Node {
+  list := newList()
+  if doctag := n.Doctag(); doctag != nil {
+    list.Doctags = append(list.Doctags, doctag)
+  }
+  list.Items = append(list.Items, n)
+  return list
+}
+
+// Describes the doctag that set the value of n (or created the map or list) for messages.
+// The file of the doctag is included when it's not the file of the doctag being transformed.
+func (t *transform) origin(n *Node) string {
+  if doctag := n.Doctag(); doctag != nil && doctag.File != t.doctag.File {
+    return fmt.Sprintf(" from doctag '%v' in %v at line %v, column %v", doctag.Name, doctag.File, doctag.Line, doctag.Column)
+  } else if doctag != nil {
+    return fmt.Sprintf(" from doctag '%v' at line %v, column %v", doctag.Name, doctag.Line, doctag.Column)
+  }
+  return ""
+}
@@ -0,0 +1,65 @@
+package hierarchy
+
+import (
+  "github.com/dschnare/doctag/parse"
+)
+
+// SliceMerge controls how Merge combines the slices of layers.
+type SliceMerge int
+
+// The slice merge policies supported by Merge.
+const (
+  // SlicesReplace replaces the slice of a lower layer with the slice of a higher layer (the default).
+  SlicesReplace SliceMerge = iota
+  // SlicesAppend appends the items of the slice of a higher layer to the slice of a lower layer.
+  SlicesAppend
+)
+
+// Merge layers trees returned by TransformTree into one tree, e.g. the tree of base content followed
+// by the trees of its overrides. Maps are merged deeply, with the keys of higher layers added after
+// the existing keys. Slices are replaced or appended to according to slices, and values (or nodes of
+// a different kind) are replaced by the higher layer. The nodes of the merged tree keep the doctags
+// that produced them, so the file of each final value is the File of its Doctag.
+// Merge never modifies the layers, but the merged tree shares the nodes that were not merged with
+// them, so modifying a node of the merged tree may modify a layer.
+func Merge(layers []*Node, slices SliceMerge) *Node {
+  merged := newMap()
+  for _,layer := range layers {
+    if layer != nil {
+      merged = mergeNodes(merged, layer, slices)
+    }
+  }
+  return merged
+}
+
+func mergeNodes(base *Node, override *Node, slices SliceMerge) *Node {
+  if base.Kind != override.Kind || base.Kind == LeafNode || (base.Kind == ListNode && slices == SlicesReplace) {
+    return override
+  }
+
+  merged := &Node{Kind: base.Kind, Doctags: concatDoctags(base.Doctags, override.Doctags)}
+
+  if base.Kind == ListNode {
+    merged.Items = append(append(merged.Items, base.Items...), override.Items...)
+    return merged
+  }
+
+  merged.Children = make(map[string]*Node, len(base.Children))
+  for _,key := range base.Keys {
+    merged.set(key, base.Children[key])
+  }
+  for _,key := range override.Keys {
+    if child,ok := merged.Children[key]; ok {
+      merged.set(key, mergeNodes(child, override.Children[key], slices))
+    } else {
+      merged.set(key, override.Children[key])
+    }
+  }
+
+  return merged
+}
+
+func concatDoctags(a []*parse.DoctagNode, b []*parse.DoctagNode) []*parse.DoctagNode {
+  doctags := make([]*parse.DoctagNode, 0, len(a) + len(b))
+  return append(append(doctags, a...), b...)
+}
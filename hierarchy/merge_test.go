@@ -0,0 +1,71 @@
+package hierarchy
+
+import (
+  "testing"
+  "github.com/dschnare/doctag/parse"
+)
+
+func TestMerge(t *testing.T) {
+  base := []*parse.DoctagNode{
+    &parse.DoctagNode{Name: "page/title", Value: "Title", Line: 1, File: "base.txt"},
+    &parse.DoctagNode{Name: "page/footer", Value: "Footer", Line: 2, File: "base.txt"},
+    &parse.DoctagNode{Name: "page/#tags", Value: "a", Line: 3, File: "base.txt"},
+    &parse.DoctagNode{Name: "meta", Value: "none", Line: 4, File: "base.txt"},
+  }
+  region := []*parse.DoctagNode{
+    &parse.DoctagNode{Name: "page/title", Value: "Region Title", Line: 1, File: "region.txt"},
+    &parse.DoctagNode{Name: "page/#tags", Value: "b", Line: 2, File: "region.txt"},
+    &parse.DoctagNode{Name: "page/lang", Value: "fr", Line: 3, File: "region.txt"},
+    &parse.DoctagNode{Name: "meta/author", Value: "Me", Line: 4, File: "region.txt"},
+  }
+
+  transformer := NewTransformer()
+  layers := make([]*Node, 0, 2)
+  for _,doctags := range [][]*parse.DoctagNode{base, region} {
+    root,err := transformer.TransformTree(doctags)
+    if err != nil {
+      t.Fatalf("unexpected error encountered : %v", err.Error())
+    }
+    layers = append(layers, root)
+  }
+
+  merged := Merge(layers, SlicesReplace)
+  testValue(merged.Interface(), map[string]interface{}{
+    "page": map[string]interface{}{
+      "title": "Region Title",
+      "footer": "Footer",
+      "tags": []interface{}{"b"},
+      "lang": "fr",
+    },
+    "meta": map[string]interface{}{"author": "Me"},
+  }, t)
+
+  files := map[string]string{
+    "page": "base.txt",
+    "page/title": "region.txt",
+    "page/footer": "base.txt",
+    "page/tags[0]": "region.txt",
+    "meta/author": "region.txt",
+  }
+  for path,file := range files {
+    if doctag := merged.Find(path, DefaultSeparator).Doctag(); doctag.File != file {
+      t.Fatalf("expected '%v' to come from %v : got %v", path, file, doctag.File)
+    }
+  }
+  if keys := merged.Child("page").Keys; len(keys) != 4 || keys[3] != "lang" {
+    t.Fatalf("expected the keys of the override to follow the base keys : got %v", keys)
+  }
+
+  merged = Merge(layers, SlicesAppend)
+  tags := merged.Find("page/tags", DefaultSeparator)
+  testValue(tags.Interface(), []interface{}{"a", "b"}, t)
+  if tags.Items[0].Doctag().File != "base.txt" || tags.Items[1].Doctag().File != "region.txt" {
+    t.Fatalf("expected the tags to come from base.txt and region.txt")
+  }
+
+  // The layers are not modified.
+  testValue(layers[0].Find("page/tags", DefaultSeparator).Interface(), []interface{}{"a"}, t)
+  if title := layers[0].Find("page/title", DefaultSeparator); title.Value != "Title" {
+    t.Fatalf("expected the base title to be unchanged : got %v", title.Value)
+  }
+}
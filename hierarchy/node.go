@@ -0,0 +1,182 @@
+package hierarchy
+
+import (
+  "strconv"
+  "github.com/dschnare/doctag/parse"
+)
+
+// NodeKind is the kind of a Node.
+type NodeKind int
+
+// The kinds of nodes in a tree returned by Transformer.TransformTree.
+const (
+  // LeafNode is a value, a string unless the Transformer converts types.
+  LeafNode NodeKind = iota
+  // MapNode is a map of keys to nodes.
+  MapNode
+  // ListNode is a list of nodes, created by '#' doctags, indices or duplicates promoted to a slice.
+  ListNode
+)
+
+// String describes the kind for messages, e.g. "a map".
+func (k NodeKind) String() string {
+  switch k {
+  case MapNode:
+    return "a map"
+  case ListNode:
+    return "a slice"
+  }
+  return "a value"
+}
+
+// A Node is a value, map or list in the tree returned by Transformer.TransformTree.
+// Every node remembers the doctags that produced it, so that problems found in the
+// hierarchy (e.g. a title that is too long) can be reported at their line and column.
+type Node struct {
+  Kind NodeKind
+  // Value is the value of a leaf.
+  Value interface{}
+  // Keys are the keys of a map in the order they were first set.
+  Keys []string
+  // Children are the nodes of a map by key.
+  Children map[string]*Node
+  // Items are the nodes of a list.
+  Items []*Node
+  // Doctags are the doctags that produced the node in document order: the doctags that set
+  // the value of a leaf (several when duplicates are concatenated), or that created or added
+  // to a map or list. Nodes created to fill a list up to an index have no doctags.
+  Doctags []*parse.DoctagNode
+  // Whether the list was promoted from a value by a duplicate.
+  promoted bool
+}
+
+func newLeaf(value interface{}) *Node {
+  return &Node{Kind: LeafNode, Value: value}
+}
+
+func newMap() *Node {
+  return &Node{Kind: MapNode, Children: make(map[string]*Node)}
+}
+
+func newList() *Node {
+  return &Node{Kind: ListNode}
+}
+
+// Sets the child of a map at key. New keys are added after the existing keys.
+func (n *Node) set(key string, child *Node) {
+  if _,ok := n.Children[key]; !ok {
+    n.Keys = append(n.Keys, key)
+  }
+  n.Children[key] = child
+}
+
+// Doctag returns the doctag that set the value of a leaf (the last one) or that created a map
+// or list (the first one). Returns nil when the node was not produced by a doctag.
+func (n *Node) Doctag() *parse.DoctagNode {
+  if len(n.Doctags) == 0 {
+    return nil
+  }
+  if n.Kind == LeafNode {
+    return n.Doctags[len(n.Doctags) - 1]
+  }
+  return n.Doctags[0]
+}
+
+// Child returns the child of a map at key, or nil.
+func (n *Node) Child(key string) *Node {
+  if n.Kind != MapNode {
+    return nil
+  }
+  return n.Children[key]
+}
+
+// Find returns the node at a path of keys delimited by separator, or nil. List items are
+// found with indices, e.g. "page/links[1]/href".
+func (n *Node) Find(path string, separator rune) *Node {
+  node := n
+
+  for _,pathName := range getPathNames(path, separator) {
+    if node == nil {
+      return nil
+    }
+    if isIndex(pathName) && node.Kind == ListNode {
+      index,err := strconv.Atoi(pathName[1:len(pathName) - 1])
+      if err != nil || index >= len(node.Items) {
+        return nil
+      }
+      node = node.Items[index]
+    } else {
+      node = node.Child(pathName)
+    }
+  }
+
+  return node
+}
+
+// Walk calls fn for n and all of its descendants in order, with the path names leading
+// to each node (the items of lists have index path names such as "[1]").
+// Walking stops at the first error returned by fn, which is returned by Walk.
+func (n *Node) Walk(fn func(path []string, node *Node) error) error {
+  return n.walk(nil, fn)
+}
+
+func (n *Node) walk(path []string, fn func(path []string, node *Node) error) error {
+  if err := fn(path, n); err != nil {
+    return err
+  }
+
+  switch n.Kind {
+  case MapNode:
+    for _,key := range n.Keys {
+      if err := n.Children[key].walk(append(path[:len(path):len(path)], key), fn); err != nil {
+        return err
+      }
+    }
+  case ListNode:
+    for k,item := range n.Items {
+      if err := item.walk(append(path[:len(path):len(path)], indexName(k)), fn); err != nil {
+        return err
+      }
+    }
+  }
+
+  return nil
+}
+
+// Interface returns the value of n as the maps, slices and values returned by Transformer.Transform.
+func (n *Node) Interface() interface{} {
+  switch n.Kind {
+  case MapNode:
+    m := make(map[string]interface{}, len(n.Children))
+    for key,child := range n.Children {
+      m[key] = child.Interface()
+    }
+    return m
+  case ListNode:
+    seq := make([]interface{}, len(n.Items))
+    for k,item := range n.Items {
+      seq[k] = item.Interface()
+    }
+    return seq
+  }
+  return n.Value
+}
+
+// Ordered returns the value of n like Interface, with OrderedMaps instead of maps.
+func (n *Node) Ordered() interface{} {
+  switch n.Kind {
+  case MapNode:
+    m := NewOrderedMap()
+    for _,key := range n.Keys {
+      m.Set(key, n.Children[key].Ordered())
+    }
+    return m
+  case ListNode:
+    seq := make([]interface{}, len(n.Items))
+    for k,item := range n.Items {
+      seq[k] = item.Ordered()
+    }
+    return seq
+  }
+  return n.Value
+}
@@ -0,0 +1,102 @@
+package hierarchy
+
+import (
+  "strings"
+  "testing"
+  "github.com/dschnare/doctag/parse"
+)
+
+func TestTransformTree(t *testing.T) {
+  doctags := []*parse.DoctagNode{
+    &parse.DoctagNode{Name: "page/title", Value: "Title", Line: 1, Column: 1},
+    &parse.DoctagNode{Name: "page/#links/rel", Value: "next", Line: 2, Column: 1},
+    &parse.DoctagNode{Name: "page/links/href", Value: "next.html", Line: 3, Column: 1},
+    &parse.DoctagNode{Name: "page/title", Value: "New Title", Line: 4, Column: 1},
+    &parse.DoctagNode{Name: "page/tags[2]", Value: "c", Line: 5, Column: 1},
+  }
+
+  root,err := NewTransformer().TransformTree(doctags)
+  if err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+
+  lines := map[string]int{
+    "page": 1,
+    "page/title": 4,
+    "page/links": 2,
+    "page/links[0]": 2,
+    "page/links[0]/href": 3,
+    "page/links[0]/rel": 2,
+    "page/tags[2]": 5,
+  }
+  for path,line := range lines {
+    node := root.Find(path, DefaultSeparator)
+    if node == nil {
+      t.Fatalf("expected a node at '%v' : got nil", path)
+    }
+    if doctag := node.Doctag(); doctag == nil || doctag.Line != line {
+      t.Fatalf("expected the node at '%v' to be produced at line %v : got %v", path, line, doctag)
+    }
+  }
+
+  if page := root.Child("page"); len(page.Doctags) != 5 || page.Kind != MapNode {
+    t.Fatalf("expected the page map to be produced by 5 doctags : got %v", len(page.Doctags))
+  }
+  if links := root.Find("page/links", DefaultSeparator); len(links.Doctags) != 2 || links.Kind != ListNode {
+    t.Fatalf("expected the links slice to be produced by 2 doctags : got %v", len(links.Doctags))
+  }
+  if title := root.Find("page/title", DefaultSeparator); title.Value != "New Title" || len(title.Doctags) != 1 {
+    t.Fatalf("expected the title to be set by the last doctag : got %v from %v doctags", title.Value, len(title.Doctags))
+  }
+  // Items created to fill the slice up to the index were not produced by a doctag.
+  if gap := root.Find("page/tags[0]", DefaultSeparator); gap == nil || gap.Doctag() != nil {
+    t.Fatalf("expected the first tag to have no doctag : got %v", gap)
+  }
+
+  for _,path := range []string{"page/missing", "page/title/x", "page/links[1]", "page/tags/x"} {
+    if node := root.Find(path, DefaultSeparator); node != nil {
+      t.Fatalf("expected no node at '%v' : got %v", path, node)
+    }
+  }
+
+  var paths []string
+  root.Walk(func (path []string, node *Node) error {
+    paths = append(paths, strings.Join(path, "/"))
+    return nil
+  })
+  expected := ",page,page/title,page/links,page/links/[0],page/links/[0]/rel,page/links/[0]/href,page/tags,page/tags/[0],page/tags/[1],page/tags/[2]"
+  if strings.Join(paths, ",") != expected {
+    t.Fatalf("expected walked paths %v : got %v", expected, strings.Join(paths, ","))
+  }
+}
+
+func TestTransformTree_Duplicates(t *testing.T) {
+  doctags := []*parse.DoctagNode{
+    &parse.DoctagNode{Name: "a", Value: "x", Line: 1, Column: 1},
+    &parse.DoctagNode{Name: "a", Value: "y", Line: 2, Column: 1},
+    &parse.DoctagNode{Name: "b", Value: "x", Line: 3, Column: 1},
+    &parse.DoctagNode{Name: "b", Value: "y", Line: 4, Column: 1},
+  }
+
+  transformer := NewTransformer()
+  transformer.Duplicates = DuplicateConcat
+
+  root,err := transformer.TransformTree(doctags)
+  if err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+  if a := root.Child("a"); a.Value != "xy" || len(a.Doctags) != 2 || a.Doctags[0].Line != 1 || a.Doctag().Line != 2 {
+    t.Fatalf("expected a concatenated value produced at lines 1 and 2 : got %v from %v doctags", a.Value, len(a.Doctags))
+  }
+
+  transformer.Duplicates = DuplicateSlice
+
+  root,err = transformer.TransformTree(doctags)
+  if err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+  b := root.Child("b")
+  if b.Kind != ListNode || len(b.Items) != 2 || b.Doctag().Line != 3 || b.Items[1].Doctag().Line != 4 {
+    t.Fatalf("expected a slice created at line 3 with an item set at line 4 : got %v", b.Interface())
+  }
+}
@@ -0,0 +1,91 @@
+package hierarchy
+
+import (
+  "sort"
+  "bytes"
+  "encoding/json"
+)
+
+// An OrderedMap is a map that remembers the order of its keys. OrderedMaps are
+// returned by Transformer.TransformOrdered (and Node.Ordered), with keys in the order they first
+// appear in the doctags, and are encoded to JSON objects in that order.
+type OrderedMap struct {
+  // Keys are the keys of the map in order.
+  Keys []string
+  // Values are the values of the map by key.
+  Values map[string]interface{}
+}
+
+// SortedKeys returns the keys of a map in sorted order, which is the order that maps
+// (unlike OrderedMaps) are flattened, formatted and generated in.
+func SortedKeys(m map[string]interface{}) []string {
+  keys := make([]string, 0, len(m))
+  for key := range m {
+    keys = append(keys, key)
+  }
+  sort.Strings(keys)
+  return keys
+}
+
+// AsMap returns the entries and keys of a map[string]interface{} or an *OrderedMap,
+// and whether value is one of them. The keys of a map are sorted (see SortedKeys),
+// while the keys of an OrderedMap are in order.
+func AsMap(value interface{}) (map[string]interface{}, []string, bool) {
+  switch v := value.(type) {
+  case map[string]interface{}:
+    return v,SortedKeys(v),true
+  case *OrderedMap:
+    return v.Values,v.Keys,true
+  }
+  return nil,nil,false
+}
+
+// NewOrderedMap returns an empty OrderedMap.
+func NewOrderedMap() *OrderedMap {
+  return &OrderedMap{Values: make(map[string]interface{})}
+}
+
+// Get returns the value of a key and whether the key exists.
+func (m *OrderedMap) Get(key string) (interface{}, bool) {
+  value,ok := m.Values[key]
+  return value,ok
+}
+
+// Set sets the value of a key. New keys are added after the existing keys.
+func (m *OrderedMap) Set(key string, value interface{}) {
+  if _,ok := m.Values[key]; !ok {
+    m.Keys = append(m.Keys, key)
+  }
+  m.Values[key] = value
+}
+
+// Len returns the number of keys in the map.
+func (m *OrderedMap) Len() int {
+  return len(m.Keys)
+}
+
+// MarshalJSON encodes the map as a JSON object with its keys in order.
+func (m *OrderedMap) MarshalJSON() ([]byte, error) {
+  var b bytes.Buffer
+
+  b.WriteByte('{')
+  for k,key := range m.Keys {
+    if k > 0 {
+      b.WriteByte(',')
+    }
+    name,err := json.Marshal(key)
+    if err != nil {
+      return nil,err
+    }
+    value,err := json.Marshal(m.Values[key])
+    if err != nil {
+      return nil,err
+    }
+    b.Write(name)
+    b.WriteByte(':')
+    b.Write(value)
+  }
+  b.WriteByte('}')
+
+  return b.Bytes(),nil
+}
@@ -0,0 +1,55 @@
+package hierarchy
+
+import (
+  "testing"
+  "encoding/json"
+  "github.com/dschnare/doctag/parse"
+)
+
+func TestTransformOrdered(t *testing.T) {
+  doctags := []*parse.DoctagNode{
+    &parse.DoctagNode{Name: "page/title", Value: "Title"},
+    &parse.DoctagNode{Name: "page/#links/rel", Value: "next"},
+    &parse.DoctagNode{Name: "page/links/href", Value: "next.html"},
+    &parse.DoctagNode{Name: "footer", Value: "Footer"},
+    &parse.DoctagNode{Name: "page/content", Value: "Content"},
+    &parse.DoctagNode{Name: "page/title", Value: "New Title"},
+    &parse.DoctagNode{Name: "meta", Value: `{"z": 1, "a": [{"y": 2, "b": 3}]}`, Attributes: map[string]string{"type": "json"}},
+    &parse.DoctagNode{Name: "meta/c", Value: "4"},
+  }
+
+  transformer := NewTransformer()
+  transformer.Types = TypesAnnotated
+
+  obj,err := transformer.TransformOrdered(doctags)
+  if err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+
+  b,err := json.Marshal(obj)
+  if err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+
+  // Keys of embedded JSON values are sorted, keys set by later doctags follow them in document order.
+  expected := `{"page":{"title":"New Title","links":[{"rel":"next","href":"next.html"}],"content":"Content"},"footer":"Footer","meta":{"a":[{"b":3,"y":2}],"z":1,"c":"4"}}`
+  if string(b) != expected {
+    t.Fatalf("expected JSON %v : got %v", expected, string(b))
+  }
+
+  if value,ok := obj.Get("footer"); !ok || value != "Footer" || obj.Len() != 3 {
+    t.Fatalf("expected the map to have 3 keys and a footer : got %v", obj)
+  }
+}
+
+func TestOrderedMap_Set(t *testing.T) {
+  m := NewOrderedMap()
+  m.Set("b", 1)
+  m.Set("a", 2)
+  m.Set("b", 3)
+
+  b,err := json.Marshal(m)
+  if err != nil || string(b) != `{"b":3,"a":2}` {
+    t.Fatalf("expected JSON {\"b\":3,\"a\":2} : got %v, %v", string(b), err)
+  }
+}
@@ -2,18 +2,19 @@ package hierarchy
 
 import (
   "testing"
+  "strings"
   "github.com/dschnare/doctag/parse"
 )
 
 func TestTransform(t *testing.T) {
   if doctags,err := parse.ParseFile("./fixtures/nested.txt"); err == nil {
     expected := map[string]interface{}{
-      "nums": &([]interface{}{"1\n", "2", "3\n", "4"}),
+      "nums": []interface{}{"1\n", "2", "3\n", "4"},
       "aa": map[string]interface{}{
-        "b": (&[]interface{}{
+        "b": []interface{}{
           map[string]interface{}{"name": "Dave\n", "title": "Plumber\n", "age": "36\n"},
           map[string]interface{}{"name": "Max\n", "title": "3D Animator\n", "age": "24\n\n"},
-        }),
+        },
       },
       "a": map[string]interface{}{
         "b": map[string]interface{}{
@@ -29,7 +30,7 @@ func TestTransform(t *testing.T) {
         },
       },
       "obj": map[string]interface{}{
-        "urls": &([]interface{}{"http://google.com1\n", "http://google.com2\n", "http://google.com3\n", "http://google.com4\n\n"}),
+        "urls": []interface{}{"http://google.com1\n", "http://google.com2\n", "http://google.com3\n", "http://google.com4\n\n"},
       },
     }
 
@@ -63,12 +64,16 @@ func testValue(value interface{}, expected interface{}, t *testing.T) {
     } else {
       t.Fatalf("expected a map type %v", value)
     }
-  case *[]interface{}:
-    slicePtr := expected.(*[]interface{})
-    if _slicePtr,ok := value.(*[]interface{}); ok {
-      testSlice(_slicePtr, slicePtr, t)
+  case []interface{}:
+    slice := expected.([]interface{})
+    if _slice,ok := value.([]interface{}); ok {
+      testSlice(_slice, slice, t)
     } else {
-      t.Fatalf("expected a pointer to a slice type")
+      t.Fatalf("expected a slice type %v", value)
+    }
+  default:
+    if value != expected {
+      t.Fatalf("expected values to be equal '%v' : got '%v'", expected, value)
     }
   }
 }
@@ -83,10 +88,7 @@ func testObj(obj map[string]interface{}, expected map[string]interface{}, t *tes
   }
 }
 
-func testSlice(slicePtr *[]interface{}, expectedPtr *[]interface{}, t *testing.T) {
-  slice := *slicePtr
-  expected := *expectedPtr
-
+func testSlice(slice []interface{}, expected []interface{}, t *testing.T) {
   if len(expected) != len(slice) {
     t.Fatalf("expected slices to be same length")
   }
@@ -94,4 +96,314 @@ func testSlice(slicePtr *[]interface{}, expectedPtr *[]interface{}, t *testing.T
   for k,v := range expected {
     testValue(slice[k], v, t)
   }
-}
\ No newline at end of file
+}
+
+func TestTransform_Indices(t *testing.T) {
+  doctags := []*parse.DoctagNode{
+    &parse.DoctagNode{Name: "page/links[1]/rel", Value: "next"},
+    &parse.DoctagNode{Name: "page/links[0]/rel", Value: "alternate"},
+    &parse.DoctagNode{Name: "page/links[1]/href", Value: "next.html"},
+    &parse.DoctagNode{Name: "page/#links/rel", Value: "prev"},
+    &parse.DoctagNode{Name: "page/links/href", Value: "prev.html"},
+    &parse.DoctagNode{Name: "page/keywords[2]", Value: "stuff"},
+    &parse.DoctagNode{Name: "page/keywords[0]", Value: "awesome"},
+    &parse.DoctagNode{Name: "page/title", Value: "Title"},
+    &parse.DoctagNode{Name: "page/title[1]", Value: "Subtitle"},
+    &parse.DoctagNode{Name: "#items/tags[1]", Value: "tag"},
+    &parse.DoctagNode{Name: "items/tags[0]", Value: "first"},
+  }
+
+  expected := map[string]interface{}{
+    "page": map[string]interface{}{
+      "links": []interface{}{
+        map[string]interface{}{"rel": "alternate"},
+        map[string]interface{}{"rel": "next", "href": "next.html"},
+        map[string]interface{}{"rel": "prev", "href": "prev.html"},
+      },
+      "keywords": []interface{}{"awesome", "", "stuff"},
+      "title": []interface{}{"Title", "Subtitle"},
+    },
+    "items": []interface{}{
+      map[string]interface{}{"tags": []interface{}{"first", "tag"}},
+    },
+  }
+
+  obj,err := Transform(doctags, true)
+  if err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+  testValue(obj, expected, t)
+}
+
+func TestTransform_InvalidIndices(t *testing.T) {
+  for _,name := range []string{"[0]", "page/#links[0]", "page/links[0][1]", "page/links[65537]", "page/links[99999999999999999999]"} {
+    doctags := []*parse.DoctagNode{&parse.DoctagNode{Name: name, Value: "value", Line: 2, Column: 3}}
+    _,err := Transform(doctags, true)
+    diagnostic,ok := err.(*parse.Diagnostic)
+    if !ok || diagnostic.Code != CodeInvalidIndex || diagnostic.Line != 2 || diagnostic.Column != 3 {
+      t.Fatalf("expected an invalid index diagnostic at 2:3 for '%v' : got %v", name, err)
+    }
+  }
+
+  doctags := []*parse.DoctagNode{
+    &parse.DoctagNode{Name: "page/links/rel", Value: "next"},
+    &parse.DoctagNode{Name: "page/links[0]/rel", Value: "prev"},
+  }
+  if _,err := Transform(doctags, true); err == nil {
+    t.Fatalf("expected an error for indexing a map : got nil")
+  }
+}
+
+func TestTransform_AppendDigits(t *testing.T) {
+  // Path names of digits prefixed with '#' append to a slice like any other '#' path name.
+  doctags := []*parse.DoctagNode{
+    &parse.DoctagNode{Name: "#2023/title", Value: "New year"},
+    &parse.DoctagNode{Name: "page/#1", Value: "a"},
+    &parse.DoctagNode{Name: "page/#1", Value: "b"},
+  }
+
+  obj,err := Transform(doctags, false)
+  if err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+  testValue(obj, map[string]interface{}{
+    "2023": []interface{}{map[string]interface{}{"title": "New year"}},
+    "page": map[string]interface{}{"1": []interface{}{"a", "b"}},
+  }, t)
+}
+
+func TestTransformer_Types(t *testing.T) {
+  doctags := []*parse.DoctagNode{
+    &parse.DoctagNode{Name: "page/count", Value: " 42\n", Attributes: map[string]string{"type": "int"}},
+    &parse.DoctagNode{Name: "page/ratio", Value: "0.5", Attributes: map[string]string{"type": "float"}},
+    &parse.DoctagNode{Name: "page/draft", Value: "true", Attributes: map[string]string{"type": "bool"}},
+    &parse.DoctagNode{Name: "page/none", Value: "", Attributes: map[string]string{"type": "null"}},
+    &parse.DoctagNode{Name: "page/id", Value: "007", Attributes: map[string]string{"type": "string"}},
+    &parse.DoctagNode{Name: "page/meta", Value: `{"tags": ["a"]}`, Attributes: map[string]string{"type": "json"}},
+    &parse.DoctagNode{Name: "page/meta/#tags", Value: "b"},
+    &parse.DoctagNode{Name: "page/plain", Value: "42"},
+  }
+
+  transformer := NewTransformer()
+  transformer.Types = TypesAnnotated
+
+  obj,err := transformer.Transform(doctags)
+  if err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+
+  page := obj["page"].(map[string]interface{})
+  if page["count"] != int64(42) || page["ratio"] != 0.5 || page["draft"] != true || page["id"] != "007" || page["plain"] != "42" {
+    t.Fatalf("expected typed values : got %v", page)
+  }
+  if v,ok := page["none"]; !ok || v != nil {
+    t.Fatalf("expected a null value : got %v", v)
+  }
+  testValue(page["meta"], map[string]interface{}{"tags": []interface{}{"a", "b"}}, t)
+
+  transformer.Types = TypesInferred
+  if obj,err = transformer.Transform(doctags); err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+  if page = obj["page"].(map[string]interface{}); page["plain"] != int64(42) || page["id"] != "007" {
+    t.Fatalf("expected inferred values : got %v", page)
+  }
+
+  for value,expected := range map[string]interface{}{"-3": int64(-3), "1e3": 1000.0, "false": false, "null": nil, "Inf": "Inf", "0x10": "0x10", "text": "text", "01234": "01234", "+5": "+5", "-0.5": -0.5, "00.5": "00.5", "+1e3": "+1e3", ".5": ".5"} {
+    if v := inferValue(value, value); v != expected {
+      t.Fatalf("expected %q to be inferred as %#v : got %#v", value, expected, v)
+    }
+  }
+}
+
+func TestTransformer_InvalidTypes(t *testing.T) {
+  transformer := NewTransformer()
+  transformer.Types = TypesAnnotated
+
+  for value,typeName := range map[string]string{"4.2": "int", "yes": "bool", "one": "float", "0": "null", "{": "json", "{} []": "json", "+5": "int", "007": "int", "+0.5": "float", "01.5": "float"} {
+    doctags := []*parse.DoctagNode{&parse.DoctagNode{Name: "value", Value: value, Attributes: map[string]string{"type": typeName}, Line: 4, Column: 2}}
+    _,err := transformer.Transform(doctags)
+    diagnostic,ok := err.(*parse.Diagnostic)
+    if !ok || diagnostic.Code != CodeInvalidValue || diagnostic.Line != 4 || diagnostic.Column != 2 {
+      t.Fatalf("expected an invalid value diagnostic at 4:2 for %q as %v : got %v", value, typeName, err)
+    }
+  }
+
+  doctags := []*parse.DoctagNode{&parse.DoctagNode{Name: "value", Value: "1", Attributes: map[string]string{"type": "number"}}}
+  if _,err := transformer.Transform(doctags); err == nil || err.(*parse.Diagnostic).Code != CodeInvalidType {
+    t.Fatalf("expected an invalid type diagnostic : got %v", err)
+  }
+
+  // Annotations are ignored unless a type mode is set.
+  transformer.Types = TypesNone
+  if obj,err := transformer.Transform(doctags); err != nil || obj["value"] != "1" {
+    t.Fatalf("expected the type attribute to be ignored : got %v, %v", obj, err)
+  }
+}
+
+func TestTransformer_Conflicts(t *testing.T) {
+  doctags := []*parse.DoctagNode{
+    &parse.DoctagNode{Name: "a", Value: "x", Line: 1, Column: 1},
+    &parse.DoctagNode{Name: "a/b", Value: "y", Line: 2, Column: 1},
+    &parse.DoctagNode{Name: "c/d", Value: "z", Line: 3, Column: 1},
+    &parse.DoctagNode{Name: "c", Value: "w", Line: 4, Column: 1},
+  }
+
+  transformer := NewTransformer()
+
+  obj,err := transformer.Transform(doctags)
+  if err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+  testValue(obj, map[string]interface{}{"a": map[string]interface{}{"b": "y"}, "c": map[string]interface{}{"d": "z"}}, t)
+
+  // A later value is ignored for a slice as well.
+  obj,err = transformer.Transform([]*parse.DoctagNode{
+    &parse.DoctagNode{Name: "page/#keywords", Value: "x"},
+    &parse.DoctagNode{Name: "page/keywords", Value: "y"},
+  })
+  if err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+  testValue(obj, map[string]interface{}{"page": map[string]interface{}{"keywords": []interface{}{"x"}}}, t)
+
+  transformer.Conflicts = ConflictLastWins
+  if obj,err = transformer.Transform(doctags); err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+  testValue(obj, map[string]interface{}{"a": map[string]interface{}{"b": "y"}, "c": "w"}, t)
+
+  transformer.Conflicts = ConflictFirstWins
+  if obj,err = transformer.Transform(doctags); err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+  testValue(obj, map[string]interface{}{"a": "x", "c": map[string]interface{}{"d": "z"}}, t)
+
+  var warnings []*parse.Diagnostic
+  transformer.Conflicts = ConflictWarn
+  transformer.WarningHandler = func (warning *parse.Diagnostic) {
+    warnings = append(warnings, warning)
+  }
+  if obj,err = transformer.Transform(doctags); err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+  testValue(obj, map[string]interface{}{"a": map[string]interface{}{"b": "y"}, "c": "w"}, t)
+  if len(warnings) != 2 || warnings[0].Code != CodeConflict || warnings[0].Line != 2 || warnings[1].Line != 4 {
+    t.Fatalf("expected two conflict warnings at lines 2 and 4 : got %v", warnings)
+  }
+
+  transformer.Conflicts = ConflictError
+  _,err = transformer.Transform(doctags)
+  diagnostic,ok := err.(*parse.Diagnostic)
+  if !ok || diagnostic.Code != CodeConflict || diagnostic.Line != 2 {
+    t.Fatalf("expected a conflict error at line 2 : got %v", err)
+  }
+  expected := "Path 'a' cannot be a map, it's already a value from doctag 'a' at line 1, column 1"
+  if diagnostic.Message != expected {
+    t.Fatalf("expected message '%v' : got '%v'", expected, diagnostic.Message)
+  }
+
+  _,err = transformer.Transform(doctags[2:])
+  if err == nil || err.(*parse.Diagnostic).Message != "Path 'c' cannot be a value, it's already a map from doctag 'c/d' at line 3, column 1" {
+    t.Fatalf("expected a conflict error for 'c' : got %v", err)
+  }
+
+  // Doctags from other files are described with their file.
+  doctags = []*parse.DoctagNode{
+    &parse.DoctagNode{Name: "a", Value: "x", Line: 1, Column: 1, File: "en.txt"},
+    &parse.DoctagNode{Name: "a/b", Value: "y", Line: 2, Column: 1, File: "fr.txt"},
+  }
+  _,err = transformer.Transform(doctags)
+  if err == nil || err.(*parse.Diagnostic).File != "fr.txt" || err.(*parse.Diagnostic).Message != "Path 'a' cannot be a map, it's already a value from doctag 'a' in en.txt at line 1, column 1" {
+    t.Fatalf("expected a conflict error in fr.txt for 'a' : got %v", err)
+  }
+}
+
+func TestTransformer_SliceConflicts(t *testing.T) {
+  transformer := NewTransformer()
+  transformer.Conflicts = ConflictError
+
+  // Appending never conflicts, and indices only conflict with items set by doctags.
+  doctags := []*parse.DoctagNode{
+    &parse.DoctagNode{Name: "list[1]/name", Value: "b"},
+    &parse.DoctagNode{Name: "list[0]", Value: "a"},
+    &parse.DoctagNode{Name: "#tags", Value: "a"},
+    &parse.DoctagNode{Name: "tags[2]/name", Value: "c"},
+    &parse.DoctagNode{Name: "#tags", Value: "d"},
+  }
+  obj,err := transformer.Transform(doctags)
+  if err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+  testValue(obj, map[string]interface{}{
+    "list": []interface{}{"a", map[string]interface{}{"name": "b"}},
+    "tags": []interface{}{"a", map[string]interface{}{}, map[string]interface{}{"name": "c"}, "d"},
+  }, t)
+
+  for _,names := range [][]string{
+    {"#tags", "tags/name"},
+    {"list[0]/name", "list[0]"},
+    {"list[0]", "list[0]/name"},
+  } {
+    doctags := []*parse.DoctagNode{
+      &parse.DoctagNode{Name: names[0], Value: "x", Line: 1},
+      &parse.DoctagNode{Name: names[1], Value: "y", Line: 2},
+    }
+    _,err := transformer.Transform(doctags)
+    if diagnostic,ok := err.(*parse.Diagnostic); !ok || diagnostic.Code != CodeConflict || !strings.Contains(diagnostic.Message, "at line 1") {
+      t.Fatalf("expected a conflict error for %v : got %v", names, err)
+    }
+  }
+}
+
+func TestTransformer_Duplicates(t *testing.T) {
+  doctags := []*parse.DoctagNode{
+    &parse.DoctagNode{Name: "a", Value: "x", Line: 1, Column: 1},
+    &parse.DoctagNode{Name: "a", Value: "y", Line: 2, Column: 1},
+    &parse.DoctagNode{Name: "list[0]", Value: "x", Line: 3, Column: 1},
+    &parse.DoctagNode{Name: "list[0]", Value: "y", Line: 4, Column: 1},
+    &parse.DoctagNode{Name: "#items", Value: "x", Line: 5, Column: 1},
+    &parse.DoctagNode{Name: "#items", Value: "y", Line: 6, Column: 1},
+    &parse.DoctagNode{Name: "a", Value: "z", Line: 7, Column: 1},
+  }
+
+  transformer := NewTransformer()
+
+  for _,test := range []struct {
+    policy DuplicatePolicy
+    a interface{}
+    list interface{}
+  }{
+    {DuplicateLastWins, "z", []interface{}{"y"}},
+    {DuplicateFirstWins, "x", []interface{}{"x"}},
+    {DuplicateWarn, "z", []interface{}{"y"}},
+    {DuplicateConcat, "x, y, z", []interface{}{"x, y"}},
+    {DuplicateSlice, []interface{}{"x", "y", "z"}, []interface{}{[]interface{}{"x", "y"}}},
+  } {
+    var warnings []*parse.Diagnostic
+    transformer.Duplicates = test.policy
+    transformer.DuplicateSeparator = ", "
+    transformer.WarningHandler = func (warning *parse.Diagnostic) {
+      warnings = append(warnings, warning)
+    }
+
+    obj,err := transformer.Transform(doctags)
+    if err != nil {
+      t.Fatalf("unexpected error encountered : %v", err.Error())
+    }
+    testValue(obj, map[string]interface{}{"a": test.a, "list": test.list, "items": []interface{}{"x", "y"}}, t)
+
+    if test.policy == DuplicateWarn && (len(warnings) != 3 || warnings[0].Code != CodeDuplicate || warnings[0].Line != 2) {
+      t.Fatalf("expected three duplicate warnings : got %v", warnings)
+    } else if test.policy != DuplicateWarn && len(warnings) > 0 {
+      t.Fatalf("expected no warnings : got %v", warnings)
+    }
+  }
+
+  transformer.Duplicates = DuplicateError
+  _,err := transformer.Transform(doctags)
+  diagnostic,ok := err.(*parse.Diagnostic)
+  if !ok || diagnostic.Code != CodeDuplicate || diagnostic.Line != 2 || diagnostic.Message != "Path 'a' already has a value from doctag 'a' at line 1, column 1" {
+    t.Fatalf("expected a duplicate error at line 2 : got %v", err)
+  }
+}
@@ -0,0 +1,297 @@
+package hierarchy
+
+import (
+  "fmt"
+  "strconv"
+  "log"
+  "regexp"
+  "strings"
+  "encoding/json"
+  "github.com/dschnare/doctag/parse"
+  "github.com/dschnare/doctag/identifier"
+)
+
+// TypeMode controls how doctag values are converted by a Transformer.
+type TypeMode int
+
+// The type modes supported by a Transformer.
+const (
+  // TypesNone keeps all values as strings (the default).
+  TypesNone TypeMode = iota
+  // TypesAnnotated converts the values of doctags that have a "type" attribute
+  // (e.g. <{ page/count type=int }>) to that type. The types are string, int,
+  // float, bool, null and json (the value is a JSON document).
+  TypesAnnotated
+  // TypesInferred converts annotated values like TypesAnnotated, and converts other
+  // values that look like an int, float, bool or null (e.g. "42", "4.2", "true" or "null").
+  // Numbers must be written like JSON numbers, so values such as "01234" or "+5" stay strings.
+  TypesInferred
+)
+
+// ConflictPolicy controls what a Transformer does when a path is used both as a map
+// (or slice) and as a value, e.g. <{ a }>x followed by <{ a/b }>y.
+type ConflictPolicy int
+
+// The conflict policies supported by a Transformer.
+const (
+  // ConflictMapsWin lets a map or slice replace an existing value, but ignores a later
+  // value for an existing map or slice (the default).
+  ConflictMapsWin ConflictPolicy = iota
+  // ConflictLastWins replaces the existing map or value.
+  ConflictLastWins
+  // ConflictFirstWins keeps the existing map or value and ignores the later doctag.
+  ConflictFirstWins
+  // ConflictWarn replaces the existing map or value and reports a warning.
+  ConflictWarn
+  // ConflictError stops the transformation with an error.
+  ConflictError
+)
+
+// DuplicatePolicy controls what a Transformer does when a path is assigned a value
+// more than once, e.g. <{ a }>x followed by <{ a }>y.
+type DuplicatePolicy int
+
+// The duplicate policies supported by a Transformer.
+const (
+  // DuplicateLastWins replaces the existing value (the default).
+  DuplicateLastWins DuplicatePolicy = iota
+  // DuplicateFirstWins keeps the existing value and ignores the later doctag.
+  DuplicateFirstWins
+  // DuplicateWarn replaces the existing value and reports a warning.
+  DuplicateWarn
+  // DuplicateError stops the transformation with an error.
+  DuplicateError
+  // DuplicateConcat joins the values as strings, separated by the DuplicateSeparator.
+  DuplicateConcat
+  // DuplicateSlice promotes the value to a slice and appends all of its duplicates.
+  DuplicateSlice
+)
+
+// TypeAttribute is the doctag attribute that annotates the type of a value.
+const TypeAttribute = "type"
+
+// A Transformer holds the configuration used to transform doctags into a map hierarchy.
+// Use NewTransformer to create a Transformer with the default configuration.
+type Transformer struct {
+  // Separator is the character used to delimit the path names of doctags.
+  Separator rune
+  // KeysToIdentifiers converts the path names to identifiers (see the identifier package).
+  KeysToIdentifiers bool
+  // Types controls how values are converted from strings.
+  Types TypeMode
+  // Conflicts controls what happens when a path is used both as a map and as a value.
+  // Conflicts are reported with the positions of both doctags.
+  Conflicts ConflictPolicy
+  // Duplicates controls what happens when a path is assigned a value more than once.
+  Duplicates DuplicatePolicy
+  // DuplicateSeparator separates the values joined by DuplicateConcat.
+  DuplicateSeparator string
+  // Logger is the optional logger to have warnings logged to.
+  Logger *log.Logger
+  // WarningHandler is the optional function called with every warning found while transforming.
+  WarningHandler func(warning *parse.Diagnostic)
+}
+
+// NewTransformer returns a Transformer that uses the default separator and keeps all values as strings.
+func NewTransformer() *Transformer {
+  return &Transformer{Separator: DefaultSeparator}
+}
+
+// Transform transforms a slice of DoctagNodes into a hierarchical map that represents a JSON object.
+func (t *Transformer) Transform(doctags []*parse.DoctagNode) (map[string]interface{}, error) {
+  root,err := t.TransformTree(doctags)
+  if err != nil {
+    return nil,err
+  }
+  return root.Interface().(map[string]interface{}),nil
+}
+
+// TransformOrdered transforms a slice of DoctagNodes into a hierarchy of OrderedMaps
+// whose keys are in the order they first appear in the doctags.
+func (t *Transformer) TransformOrdered(doctags []*parse.DoctagNode) (*OrderedMap, error) {
+  root,err := t.TransformTree(doctags)
+  if err != nil {
+    return nil,err
+  }
+  return root.Ordered().(*OrderedMap),nil
+}
+
+// TransformTree transforms a slice of DoctagNodes into a tree of Nodes, with a map at its root.
+// Every node of the tree remembers the doctags that produced it.
+func (t *Transformer) TransformTree(doctags []*parse.DoctagNode) (*Node, error) {
+  root := newMap()
+  state := &transform{Transformer: t}
+
+  for _,doctag := range doctags {
+    value,err := t.value(doctag)
+    if err != nil {
+      return nil,err
+    }
+
+    state.doctag = doctag
+    leaf := toNode(value, doctag)
+    pathNames := getPathNames(doctag.Name, t.Separator)
+    last := len(pathNames) - 1
+    o := root
+    path := ""
+
+    for g,pathName := range pathNames {
+      if pathName == "#" {
+        return nil,nodeError(doctag, CodeInvalidPath, "Path cannot equal '#'")
+      }
+      if isIndex(pathName) {
+        if o.Kind != ListNode {
+          return nil,nodeError(doctag, CodeInvalidIndex, fmt.Sprintf("Index '%v' must follow a path name", pathName))
+        }
+        index,convErr := strconv.Atoi(pathName[1:len(pathName) - 1])
+        if convErr != nil || index > MaxIndex {
+          return nil,nodeError(doctag, CodeInvalidIndex, fmt.Sprintf("Index '%v' cannot be greater than %v", pathName, MaxIndex))
+        }
+        o,path,err = state.resolveIndex(o, path, index, leaf, g == last)
+      } else {
+        if t.KeysToIdentifiers {
+          // When we convert to an identifier we prserve the "#" prefix.
+          // The prefix is trimmed when actually saving to the map.
+          pathName = identifier.ToIdentifierFunc(pathName, identifierValidRuneFunc)
+          if len(pathName) == 0 {
+            return nil,nodeError(doctag, CodeEmptyPath, "After converting to an identifier, path is empty")
+          }
+        }
+        if g < last && isIndex(pathNames[g + 1]) {
+          if strings.HasPrefix(pathName, "#") {
+            return nil,nodeError(doctag, CodeInvalidIndex, fmt.Sprintf("Path '%v' appends to a slice and cannot be indexed", pathName))
+          }
+          o,path,err = state.resolveList(o, path, pathName)
+        } else if g == last {
+          err = state.assign(o, path, pathName, leaf)
+        } else {
+          o,path,err = state.resolve(o, path, pathName)
+        }
+      }
+
+      if err != nil {
+        return nil,err
+      }
+      // The doctag is ignored because of a conflict.
+      if o == nil {
+        break
+      }
+    }
+  }
+
+  return root,nil
+}
+
+// Converts the value of doctag according to the type mode.
+// Values are trimmed of whitespace before being converted to a type other than string.
+func (t *Transformer) value(doctag *parse.DoctagNode) (interface{}, error) {
+  if t.Types == TypesNone {
+    return doctag.Value,nil
+  }
+
+  text := strings.TrimSpace(doctag.Value)
+  typeName,annotated := doctag.Attributes[TypeAttribute]
+
+  if !annotated {
+    if t.Types == TypesInferred {
+      return inferValue(doctag.Value, text),nil
+    }
+    return doctag.Value,nil
+  }
+
+  var (
+    value interface{}
+    err error
+  )
+
+  switch typeName {
+  case "string":
+    return doctag.Value,nil
+  case "int":
+    if value,err = strconv.ParseInt(text, 10, 64); err == nil && !intPattern.MatchString(text) {
+      err = fmt.Errorf("expected an integer without a '+' sign or leading zeros")
+    }
+  case "float":
+    if value,err = strconv.ParseFloat(text, 64); err == nil && !floatPattern.MatchString(text) {
+      err = fmt.Errorf("expected a decimal number without a '+' sign or leading zeros")
+    }
+  case "bool":
+    if text != "true" && text != "false" {
+      err = fmt.Errorf("expected true or false")
+    }
+    value = text == "true"
+  case "null":
+    if len(text) > 0 && text != "null" {
+      err = fmt.Errorf("expected null or an empty value")
+    }
+  case "json":
+    decoder := json.NewDecoder(strings.NewReader(text))
+    if err = decoder.Decode(&value); err == nil && decoder.More() {
+      err = fmt.Errorf("unexpected data after the JSON value")
+    }
+  default:
+    return nil,nodeError(doctag, CodeInvalidType, fmt.Sprintf("Unknown type '%v', expected string, int, float, bool, null or json", typeName))
+  }
+
+  if err != nil {
+    if numErr,ok := err.(*strconv.NumError); ok {
+      err = numErr.Err
+    }
+    return nil,nodeError(doctag, CodeInvalidValue, fmt.Sprintf("Cannot convert %q to %v: %v", text, typeName, err.Error()))
+  }
+
+  return value,nil
+}
+
+// Returns the typed value that text looks like, or value when it looks like a string.
+func inferValue(value string, text string) interface{} {
+  switch text {
+  case "true":
+    return true
+  case "false":
+    return false
+  case "null":
+    return nil
+  }
+  if !floatPattern.MatchString(text) {
+    return value
+  }
+  if i,err := strconv.ParseInt(text, 10, 64); err == nil && intPattern.MatchString(text) {
+    return i
+  }
+  if f,err := strconv.ParseFloat(text, 64); err == nil {
+    return f
+  }
+  return value
+}
+
+// Numbers are written like JSON numbers: without a '+' sign or leading zeros (e.g. zip codes
+// such as "01234" stay strings), and never as "Inf", "NaN" or hexadecimal numbers.
+var (
+  intPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)$`)
+  floatPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+)
+
+// Converts a value to a node produced by doctag. Decoded JSON maps and slices become
+// map and list nodes, so that following doctags can add to them. The keys of maps are sorted.
+func toNode(value interface{}, doctag *parse.DoctagNode) *Node {
+  var n *Node
+
+  switch v := value.(type) {
+  case map[string]interface{}:
+    n = newMap()
+    for _,key := range SortedKeys(v) {
+      n.set(key, toNode(v[key], doctag))
+    }
+  case []interface{}:
+    n = newList()
+    for _,item := range v {
+      n.Items = append(n.Items, toNode(item, doctag))
+    }
+  default:
+    n = newLeaf(value)
+  }
+
+  n.Doctags = []*parse.DoctagNode{doctag}
+  return n
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+  "fmt"
+  "os"
+  "sort"
+  "strings"
+  "path/filepath"
+  "github.com/dschnare/doctag/parse"
+  "github.com/dschnare/doctag/hierarchy"
+)
+
+// Reports whether the arguments name more than a single file, i.e. several paths,
+// a directory or a glob pattern.
+func isMultiInput(args []string) bool {
+  if len(args) != 1 {
+    return true
+  }
+  info,err := os.Stat(args[0])
+  if err != nil {
+    return isPattern(args[0])
+  }
+  return info.IsDir()
+}
+
+func isPattern(arg string) bool {
+  return strings.ContainsAny(arg, "*?[")
+}
+
+// Expands the file, directory and glob pattern arguments into the files to read, in argument order.
+// Directories are read recursively for the files whose name matches the include pattern,
+// skipping hidden files and directories and the output file. Files named more than once are only read once.
+func expandInputs(args []string) ([]string, error) {
+  var files []string
+  seen := make(map[string]bool)
+
+  add := func (file string) {
+    if file = filepath.Clean(file); !seen[file] {
+      seen[file] = true
+      files = append(files, file)
+    }
+  }
+
+  for _,arg := range args {
+    matches := []string{arg}
+
+    if _,err := os.Stat(arg); err != nil && isPattern(arg) {
+      if matches,err = filepath.Glob(arg); err != nil {
+        return nil,&codeError{code: exitUsage, err: fmt.Errorf("Invalid pattern '%v': %v", arg, err.Error())}
+      } else if len(matches) == 0 {
+        // Like a file that does not exist, a pattern without files is an I/O error.
+        return nil,&codeError{code: exitIO, err: fmt.Errorf("No files match '%v'", arg)}
+      }
+      sort.Strings(matches)
+    }
+
+    for _,match := range matches {
+      // Files that cannot be read are reported with the other files.
+      info,err := os.Stat(match)
+      if err != nil || !info.IsDir() {
+        add(match)
+        continue
+      }
+
+      err = filepath.Walk(match, func (path string, info os.FileInfo, err error) error {
+        if err != nil {
+          return err
+        }
+        hidden := path != match && strings.HasPrefix(info.Name(), ".")
+        if info.IsDir() && hidden {
+          return filepath.SkipDir
+        }
+        if info.Mode().IsRegular() && !hidden && !isOutput(path) {
+          if ok,_ := filepath.Match(include, info.Name()); ok {
+            add(path)
+          }
+        }
+        return nil
+      })
+      if err != nil {
+        return nil,err
+      }
+    }
+  }
+
+  return files,nil
+}
+
+// Reports whether file is the output file.
+func isOutput(file string) bool {
+  if len(output) == 0 {
+    return false
+  }
+  a,errA := filepath.Abs(file)
+  b,errB := filepath.Abs(output)
+  return errA == nil && errB == nil && a == b
+}
+
+// Returns the path of file relative to the working directory with '/' separators,
+// or the path as given when it's outside of the working directory.
+func relativePath(file string) string {
+  if wd,err := os.Getwd(); err == nil {
+    if rel,ok := parse.RelativePath(file, wd); ok {
+      file = rel
+    }
+  }
+  return filepath.ToSlash(file)
+}
+
+// Reads and transforms every input file, then writes either a map of hierarchies keyed by
+// relative file path, one hierarchy of the doctags of all files (with -merge) or the hierarchies
+// merged as layers (with -layers). Errors are reported for each file that cannot be read or
+// transformed, and nothing is written if there were any.
+func doInputs() error {
+  files,err := expandInputs(inputs)
+  if err != nil {
+    return err
+  }
+
+  var (
+    merged []*parse.DoctagNode
+    trees []*hierarchy.Node
+    failed int
+  )
+
+  for _,file := range files {
+    doctags,err := newParser().ParseFile(file)
+    if err == nil && !merge {
+      var root *hierarchy.Node
+      if root,err = newTransformer(doctags).TransformTree(doctags); err == nil {
+        trees = append(trees, root)
+      }
+    }
+    if err != nil {
+      reportError(err)
+      failed++
+      continue
+    }
+    merged = append(merged, doctags...)
+  }
+
+  if failed > 0 {
+    return errReported
+  }
+
+  var value interface{}
+
+  if merge {
+    if value,err = transformValue(merged); err != nil {
+      return err
+    }
+  } else if layers {
+    slices := hierarchy.SlicesReplace
+    if layerSlices == "append" {
+      slices = hierarchy.SlicesAppend
+    }
+    value = treeValue(hierarchy.Merge(trees, slices))
+  } else {
+    byFile := hierarchy.NewOrderedMap()
+    for k,root := range trees {
+      byFile.Set(relativePath(files[k]), treeValue(root))
+    }
+    value = byFile
+    if !ordered {
+      value = byFile.Values
+    }
+  }
+
+  writer,err := createWriter()
+  if err != nil {
+    return err
+  }
+
+  return writeValue(writer, value)
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+  "testing"
+)
+
+func TestExpandInputs_NoMatch(t *testing.T) {
+  _,err := expandInputs([]string{"./fixtures-that-do-not-exist/*.txt"})
+  if err == nil {
+    t.Fatalf("expected an error for a pattern without files")
+  }
+  if code := exitCode(err); code != exitIO {
+    t.Fatalf("expected exit code %v : got %v", exitIO, code)
+  }
+
+  if _,err = expandInputs([]string{"[*.txt"}); err == nil || exitCode(err) != exitUsage {
+    t.Fatalf("expected exit code %v for an invalid pattern : got %v", exitUsage, err)
+  }
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+  "fmt"
+  "os"
+  "errors"
+  "encoding/json"
+  "github.com/dschnare/doctag/parse"
+  "github.com/dschnare/doctag/lint"
+)
+
+// The number of findings of the last lint run.
+var findings int
+
+// Lints the input files (or standard in) and writes the findings in the lint format.
+func doLint() (err error) {
+  linter := lint.NewLinter()
+  linter.Parser = newParser()
+  linter.Transformer.Separator = tagSeparator
+  linter.Transformer.Types = typeMode()
+
+  var results []*parse.Diagnostic
+  findings = 0
+
+  if len(fileName) == 0 && len(inputs) == 0 {
+    if results,err = linter.Lint(os.Stdin); err != nil {
+      return
+    }
+  } else {
+    files := []string{fileName}
+    if len(inputs) > 0 {
+      if files,err = expandInputs(inputs); err != nil {
+        return
+      }
+    }
+    for _,file := range files {
+      fileResults,err := linter.LintFile(file)
+      if err != nil {
+        var diagnostic *parse.Diagnostic
+        if !errors.As(err, &diagnostic) {
+          diagnostic = &parse.Diagnostic{Code: parse.CodeRead, Severity: parse.SeverityError, File: file, Message: err.Error(), Err: err}
+        } else if diagnostic.Code == parse.CodeInvalidConfig {
+          return err
+        }
+        // A file that cannot be read is a finding, so that the other files are still checked.
+        fileResults = []*parse.Diagnostic{diagnostic}
+      }
+      results = append(results, fileResults...)
+    }
+  }
+
+  findings = len(results)
+
+  out,err := createWriter()
+  if err != nil {
+    return
+  }
+
+  switch lintFormat {
+  case "json":
+    if results == nil {
+      results = []*parse.Diagnostic{}
+    }
+    encoder := json.NewEncoder(out)
+    if prettyPrint {
+      encoder.SetIndent("", "  ")
+    }
+    err = encoder.Encode(results)
+  case "sarif":
+    err = lint.WriteSARIF(out, results)
+  default:
+    for _,result := range results {
+      fmt.Fprintf(out, "%v (%v)\n", formatDiagnostic(result), result.Code)
+    }
+  }
+
+  if err == nil {
+    err = out.Flush()
+  }
+
+  return
+}
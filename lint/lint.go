@@ -0,0 +1,191 @@
+/*
+Package lint checks doctag documents for suspicious constructs.
+
+A Linter reports the warnings of the parser (unclosed tags, empty names,
+stray suffixes, ...), except for skipped doctags, and the problems found by transforming the doctags
+into a hierarchy:
+
+  - paths that are assigned a value more than once (duplicates)
+  - paths used both as a value and as a map (conflicts)
+  - paths that are empty after converting to identifiers, and other
+    invalid paths, indices and typed values
+  - doctag names that differ only by whitespace or case
+
+Findings are *parse.Diagnostic values, ordered by their position in the
+document. WriteSARIF writes findings as a SARIF log for code scanning tools.
+
+Example:
+
+  findings,err := lint.NewLinter().LintFile("content.txt")
+  for _,finding := range findings {
+    fmt.Println(finding.Error())
+  }
+*/
+package lint
+
+import (
+  "io"
+  "sort"
+  "errors"
+  "strings"
+  "fmt"
+  "github.com/dschnare/doctag/parse"
+  "github.com/dschnare/doctag/hierarchy"
+)
+
+// CodeSimilarNames reports a doctag name that differs from a previous name only by whitespace or case.
+const CodeSimilarNames parse.Code = "similar-names"
+
+// A Linter holds the configuration used to check doctag documents.
+// Use NewLinter to create a Linter with the default configuration.
+type Linter struct {
+  // Parser parses the documents. Its warnings are reported as findings.
+  Parser *parse.Parser
+  // Transformer holds the separator and the type mode used to check the hierarchy.
+  // Its policies are ignored: duplicates and conflicts are always reported.
+  Transformer *hierarchy.Transformer
+}
+
+// NewLinter returns a Linter that uses the default parser and transformer configuration.
+func NewLinter() *Linter {
+  return &Linter{Parser: parse.NewParser(), Transformer: hierarchy.NewTransformer()}
+}
+
+// LintFile checks a doctag file. The returned error is only for files that cannot be read.
+func (l *Linter) LintFile(fileName string) ([]*parse.Diagnostic, error) {
+  return l.lint(func (parser *parse.Parser) ([]*parse.DoctagNode, error) {
+    return parser.ParseFile(fileName)
+  })
+}
+
+// Lint checks a doctag document read from reader. The returned error is only for documents that cannot be read.
+func (l *Linter) Lint(reader io.Reader) ([]*parse.Diagnostic, error) {
+  return l.lint(func (parser *parse.Parser) ([]*parse.DoctagNode, error) {
+    return parser.Parse(reader)
+  })
+}
+
+func (l *Linter) lint(parseFunc func (parser *parse.Parser) ([]*parse.DoctagNode, error)) ([]*parse.Diagnostic, error) {
+  var findings []*parse.Diagnostic
+
+  parser := *l.Parser
+  parser.Logger = nil
+  parser.WarningHandler = func (warning *parse.Diagnostic) {
+    // Skipped doctags (e.g. closers such as <{!}>) are intentional.
+    if warning.Code != parse.CodeSkippedTag {
+      findings = append(findings, warning)
+    }
+  }
+
+  // The doctags parsed before an error are still checked.
+  doctags,err := parseFunc(&parser)
+  if err != nil {
+    var diagnostic *parse.Diagnostic
+    if !errors.As(err, &diagnostic) || diagnostic.Code == parse.CodeRead || diagnostic.Code == parse.CodeInvalidConfig {
+      return nil,err
+    }
+    findings = append(findings, diagnostic)
+  }
+
+  findings = append(findings, l.LintDoctags(doctags)...)
+  sortFindings(findings)
+
+  return findings,nil
+}
+
+// LintDoctags checks the hierarchy of parsed doctags.
+func (l *Linter) LintDoctags(doctags []*parse.DoctagNode) []*parse.Diagnostic {
+  var findings, warnings []*parse.Diagnostic
+
+  transformer := *l.Transformer
+  transformer.KeysToIdentifiers = true
+  transformer.Conflicts = hierarchy.ConflictWarn
+  transformer.Duplicates = hierarchy.DuplicateWarn
+  transformer.Logger = nil
+  transformer.WarningHandler = func (warning *parse.Diagnostic) {
+    warnings = append(warnings, warning)
+  }
+
+  // Doctags that cannot be transformed on their own are reported and left out,
+  // so that the others are still checked together.
+  valid := make([]*parse.DoctagNode, 0, len(doctags))
+  for _,doctag := range doctags {
+    if _,err := transformer.TransformTree([]*parse.DoctagNode{doctag}); err != nil {
+      findings = append(findings, err.(*parse.Diagnostic))
+    } else {
+      valid = append(valid, doctag)
+    }
+  }
+
+  // A doctag that cannot be transformed after the doctags before it (e.g. it indexes a map)
+  // is reported and left out in turn, until the others transform together. Only the warnings
+  // of that last transformation are reported.
+  for {
+    warnings = nil
+    _,err := transformer.TransformTree(valid)
+    if err == nil {
+      break
+    }
+    findings = append(findings, err.(*parse.Diagnostic))
+
+    // Doctags are transformed in order and the transformation stops at the first error, so once
+    // a prefix of the doctags fails every longer prefix fails too, and the search finds the first
+    // doctag that fails.
+    k := sort.Search(len(valid), func (i int) bool {
+      _,err := transformer.TransformTree(valid[:i + 1])
+      return err != nil
+    })
+    valid = append(valid[:k:k], valid[k + 1:]...)
+  }
+  findings = append(findings, warnings...)
+
+  findings = append(findings, similarNames(doctags)...)
+  sortFindings(findings)
+
+  return findings
+}
+
+// Reports the doctag names that differ from a previous name only by whitespace or case, once per name.
+func similarNames(doctags []*parse.DoctagNode) []*parse.Diagnostic {
+  var findings []*parse.Diagnostic
+  firsts := make(map[string]*parse.DoctagNode)
+  reported := make(map[string]bool)
+
+  for _,doctag := range doctags {
+    key := strings.ToLower(strings.Join(strings.Fields(doctag.Name), ""))
+    first,ok := firsts[key]
+    if !ok {
+      firsts[key] = doctag
+      continue
+    }
+    if first.Name == doctag.Name || reported[doctag.Name] {
+      continue
+    }
+    reported[doctag.Name] = true
+    findings = append(findings, &parse.Diagnostic{
+      Code: CodeSimilarNames,
+      Severity: parse.SeverityWarning,
+      File: doctag.File,
+      Line: doctag.Line,
+      Column: doctag.Column,
+      Offset: doctag.Tag.Start.Offset,
+      Message: fmt.Sprintf("Doctag '%v' differs from doctag '%v' at line %v, column %v only by whitespace or case", doctag.Name, first.Name, first.Line, first.Column),
+    })
+  }
+
+  return findings
+}
+
+// Orders findings by file and position.
+func sortFindings(findings []*parse.Diagnostic) {
+  sort.SliceStable(findings, func (i, j int) bool {
+    a,b := findings[i],findings[j]
+    if a.File != b.File {
+      return a.File < b.File
+    }
+    if a.Line != b.Line {
+      return a.Line < b.Line
+    }
+    return a.Column < b.Column
+  })
+}
@@ -0,0 +1,172 @@
+package lint
+
+import (
+  "os"
+  "bytes"
+  "strings"
+  "path/filepath"
+  "testing"
+  "encoding/json"
+  "github.com/dschnare/doctag/parse"
+  "github.com/dschnare/doctag/hierarchy"
+)
+
+func TestLint(t *testing.T) {
+  doc := strings.Join([]string{
+    "<{ page/title }>Title<{!}>",
+    "<{ page/title }>Other Title<{!}>",
+    "<{ page/links }>none<{!}>",
+    "<{ page/links/href }>next.html<{!}>",
+    "<{ page/--- }>gone<{!}>",
+    "<{ Page/Title }>Title<{!}>",
+    "<{ page/# }>x<{!}>",
+    "<{ }>",
+    "<{ footer }>Footer<{!}>",
+  }, "\n")
+
+  findings,err := NewLinter().Lint(strings.NewReader(doc))
+  if err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+
+  expected := []struct {
+    code parse.Code
+    line int
+  }{
+    {hierarchy.CodeDuplicate, 2},
+    {hierarchy.CodeConflict, 4},
+    {hierarchy.CodeEmptyPath, 5},
+    {CodeSimilarNames, 6},
+    {hierarchy.CodeInvalidPath, 7},
+    {parse.CodeEmptyName, 8},
+    {parse.CodeUnclosedTag, 9},
+  }
+  if len(findings) != len(expected) {
+    t.Fatalf("expected %v findings : got %v", len(expected), findings)
+  }
+  for k,finding := range findings {
+    if finding.Code != expected[k].code || finding.Line != expected[k].line {
+      t.Fatalf("expected finding %v to be %v at line %v : got %v at line %v", k, expected[k].code, expected[k].line, finding.Code, finding.Line)
+    }
+  }
+
+  findings,err = NewLinter().Lint(strings.NewReader("<{ a }>x<{!}>\n<{ b/c }>y<{!}>"))
+  if err != nil || len(findings) != 0 {
+    t.Fatalf("expected no findings : got %v, %v", findings, err)
+  }
+}
+
+func TestLint_SeveralErrors(t *testing.T) {
+  doc := strings.Join([]string{
+    "<{ a/b }>x<{!}>",
+    "<{ a[0] }>y<{!}>",
+    "<{ c/d }>x<{!}>",
+    "<{ c[1] }>z<{!}>",
+    "<{ c/d }>w<{!}>",
+  }, "\n")
+
+  findings,err := NewLinter().Lint(strings.NewReader(doc))
+  if err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+
+  expected := []struct {
+    code parse.Code
+    line int
+  }{
+    {hierarchy.CodeInvalidIndex, 2},
+    {hierarchy.CodeInvalidIndex, 4},
+    {hierarchy.CodeDuplicate, 5},
+  }
+  if len(findings) != len(expected) {
+    t.Fatalf("expected %v findings : got %v", len(expected), findings)
+  }
+  for k,finding := range findings {
+    if finding.Code != expected[k].code || finding.Line != expected[k].line {
+      t.Fatalf("expected finding %v to be %v at line %v : got %v at line %v", k, expected[k].code, expected[k].line, finding.Code, finding.Line)
+    }
+  }
+}
+
+func TestLintDoctags_LeaveOut(t *testing.T) {
+  doctags := []*parse.DoctagNode{
+    &parse.DoctagNode{Name: "list/name", Value: "a", Line: 1, Column: 1},
+    &parse.DoctagNode{Name: "list[1]", Value: "b", Line: 2, Column: 1},
+    &parse.DoctagNode{Name: "list/title", Value: "c", Line: 3, Column: 1},
+    &parse.DoctagNode{Name: "list/title", Value: "d", Line: 4, Column: 1},
+  }
+
+  // The doctag at line 3 fails after the one at line 2, but is valid once that one is left out,
+  // and the one at line 4 is then a duplicate of it.
+  for i := 3; i <= 4; i++ {
+    if _,err := hierarchy.NewTransformer().TransformTree(doctags[:i]); err == nil {
+      t.Fatalf("expected the first %v doctags to fail", i)
+    }
+  }
+
+  findings := NewLinter().LintDoctags(doctags)
+
+  expected := []struct {
+    code parse.Code
+    line int
+  }{
+    {hierarchy.CodeInvalidIndex, 2},
+    {hierarchy.CodeDuplicate, 4},
+  }
+  if len(findings) != len(expected) {
+    t.Fatalf("expected %v findings : got %v", len(expected), findings)
+  }
+  for k,finding := range findings {
+    if finding.Code != expected[k].code || finding.Line != expected[k].line {
+      t.Fatalf("expected finding %v to be %v at line %v : got %v at line %v", k, expected[k].code, expected[k].line, finding.Code, finding.Line)
+    }
+  }
+}
+
+func TestWriteSARIF(t *testing.T) {
+  findings := []*parse.Diagnostic{
+    &parse.Diagnostic{Code: hierarchy.CodeDuplicate, Severity: parse.SeverityWarning, File: "content.txt", Line: 2, Column: 1, Message: "duplicate"},
+    &parse.Diagnostic{Code: hierarchy.CodeConflict, Severity: parse.SeverityError, Line: 3, Column: 4, Message: "conflict"},
+  }
+
+  var b bytes.Buffer
+  if err := WriteSARIF(&b, findings); err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+
+  var log sarifLog
+  if err := json.Unmarshal(b.Bytes(), &log); err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+  if log.Version != SARIFVersion || len(log.Runs) != 1 || len(log.Runs[0].Results) != 2 {
+    t.Fatalf("expected a run with 2 results : got %v", b.String())
+  }
+  if rules := log.Runs[0].Tool.Driver.Rules; len(rules) != 2 || rules[0].ID != "conflict" || rules[1].ID != "duplicate" {
+    t.Fatalf("expected the conflict and duplicate rules : got %v", rules)
+  }
+
+  first := log.Runs[0].Results[0]
+  if first.RuleID != "duplicate" || first.Level != "warning" || first.Locations[0].PhysicalLocation.ArtifactLocation.URI != "content.txt" || first.Locations[0].PhysicalLocation.Region.StartLine != 2 {
+    t.Fatalf("expected a duplicate warning in content.txt at line 2 : got %v", b.String())
+  }
+  second := log.Runs[0].Results[1]
+  if second.Level != "error" || second.Locations[0].PhysicalLocation.ArtifactLocation != nil || second.Locations[0].PhysicalLocation.Region.StartColumn != 4 {
+    t.Fatalf("expected a conflict error at column 4 without a file : got %v", b.String())
+  }
+
+  wd,_ := os.Getwd()
+  if base := log.Runs[0].OriginalURIBaseIDs[sarifSourceRoot]; base.URI != fileURI(wd) + "/" || !strings.HasPrefix(base.URI, "file:///") {
+    t.Fatalf("expected %v to be the working directory : got %v", sarifSourceRoot, base.URI)
+  }
+  if location := first.Locations[0].PhysicalLocation.ArtifactLocation; location.URIBaseID != sarifSourceRoot {
+    t.Fatalf("expected content.txt to be relative to %v : got %v", sarifSourceRoot, location.URIBaseID)
+  }
+
+  outside := filepath.Join(filepath.Dir(wd), "other dir", "content.txt")
+  if location := artifactLocation(outside, wd); location.URIBaseID != "" || !strings.HasSuffix(location.URI, "/other%20dir/content.txt") || !strings.HasPrefix(location.URI, "file:///") {
+    t.Fatalf("expected an absolute file URI for a file outside of the working directory : got %v", location.URI)
+  }
+  if location := artifactLocation(filepath.Join(wd, "sub", "a b.txt"), wd); location.URI != "sub/a%20b.txt" {
+    t.Fatalf("expected an escaped relative URI : got %v", location.URI)
+  }
+}
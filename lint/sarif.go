@@ -0,0 +1,154 @@
+package lint
+
+import (
+  "io"
+  "os"
+  "sort"
+  "strings"
+  "net/url"
+  "encoding/json"
+  "path/filepath"
+  "github.com/dschnare/doctag/parse"
+)
+
+// The SARIF version written by WriteSARIF.
+const SARIFVersion = "2.1.0"
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// The base of the URIs of files relative to the working directory.
+const sarifSourceRoot = "%SRCROOT%"
+
+type sarifLog struct {
+  Schema string `json:"$schema"`
+  Version string `json:"version"`
+  Runs []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+  Tool sarifTool `json:"tool"`
+  OriginalURIBaseIDs map[string]sarifArtifactLocation `json:"originalUriBaseIds,omitempty"`
+  Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+  Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+  Name string `json:"name"`
+  InformationURI string `json:"informationUri"`
+  Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+  ID string `json:"id"`
+}
+
+type sarifResult struct {
+  RuleID string `json:"ruleId"`
+  Level string `json:"level"`
+  Message sarifMessage `json:"message"`
+  Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+  Text string `json:"text"`
+}
+
+type sarifLocation struct {
+  PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+  ArtifactLocation *sarifArtifactLocation `json:"artifactLocation,omitempty"`
+  Region *sarifRegion `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+  URI string `json:"uri"`
+  URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+  StartLine int `json:"startLine"`
+  StartColumn int `json:"startColumn,omitempty"`
+}
+
+// WriteSARIF writes findings as a SARIF log with a single run of the doctag tool.
+// The code of each finding is its rule. Files in the working directory are written as URIs
+// relative to the %SRCROOT% base, which the log maps to the working directory, and other
+// files as absolute file URIs.
+func WriteSARIF(writer io.Writer, findings []*parse.Diagnostic) error {
+  run := sarifRun{
+    Tool: sarifTool{Driver: sarifDriver{Name: "doctag", InformationURI: "https://github.com/dschnare/doctag", Rules: []sarifRule{}}},
+    Results: []sarifResult{},
+  }
+
+  wd,err := os.Getwd()
+  if err == nil {
+    run.OriginalURIBaseIDs = map[string]sarifArtifactLocation{sarifSourceRoot: {URI: fileURI(wd) + "/"}}
+  }
+
+  rules := make(map[parse.Code]bool)
+  for _,finding := range findings {
+    if !rules[finding.Code] {
+      rules[finding.Code] = true
+      run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: string(finding.Code)})
+    }
+
+    result := sarifResult{
+      RuleID: string(finding.Code),
+      Level: "warning",
+      Message: sarifMessage{Text: finding.Message},
+    }
+    if finding.Severity == parse.SeverityError {
+      result.Level = "error"
+    }
+
+    var location sarifPhysicalLocation
+    if len(finding.File) > 0 {
+      location.ArtifactLocation = artifactLocation(finding.File, wd)
+    }
+    if finding.Line > 0 {
+      location.Region = &sarifRegion{StartLine: finding.Line, StartColumn: finding.Column}
+    }
+    if location.ArtifactLocation != nil || location.Region != nil {
+      result.Locations = []sarifLocation{{PhysicalLocation: location}}
+    }
+
+    run.Results = append(run.Results, result)
+  }
+
+  sort.Slice(run.Tool.Driver.Rules, func (i, j int) bool {
+    return run.Tool.Driver.Rules[i].ID < run.Tool.Driver.Rules[j].ID
+  })
+
+  encoder := json.NewEncoder(writer)
+  encoder.SetIndent("", "  ")
+  return encoder.Encode(sarifLog{Schema: sarifSchema, Version: SARIFVersion, Runs: []sarifRun{run}})
+}
+
+// Returns the location of file relative to the working directory wd when it's inside of it,
+// otherwise as an absolute file URI.
+func artifactLocation(file string, wd string) *sarifArtifactLocation {
+  abs,err := filepath.Abs(file)
+  if err != nil {
+    return &sarifArtifactLocation{URI: (&url.URL{Path: filepath.ToSlash(file)}).String()}
+  }
+
+  if rel,ok := parse.RelativePath(abs, wd); ok {
+    return &sarifArtifactLocation{URI: (&url.URL{Path: filepath.ToSlash(rel)}).String(), URIBaseID: sarifSourceRoot}
+  }
+
+  return &sarifArtifactLocation{URI: fileURI(abs)}
+}
+
+// Returns the file URI of an absolute path, e.g. "file:///home/content.txt" or "file:///C:/content.txt".
+func fileURI(path string) string {
+  path = filepath.ToSlash(path)
+  if !strings.HasPrefix(path, "/") {
+    path = "/" + path
+  }
+  return (&url.URL{Scheme: "file", Path: path}).String()
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+  "testing"
+  "strings"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+)
+
+func TestLint_UnreadableFile(t *testing.T) {
+  dir,err := ioutil.TempDir("", "doctag")
+  if err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+  defer os.RemoveAll(dir)
+
+  input := filepath.Join(dir, "content.txt")
+  missing := filepath.Join(dir, "missing.txt")
+  out := filepath.Join(dir, "findings.txt")
+  if err = ioutil.WriteFile(input, []byte("<{ a }>x<{ a }>y"), 0644); err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+
+  parseArgs([]string{"-output=" + out, "lint", missing, input})
+  if err = run(); err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+
+  text,err := ioutil.ReadFile(out)
+  if err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+
+  if findings != 2 || !strings.Contains(string(text), "(read-error)") || !strings.Contains(string(text), "(duplicate)") {
+    t.Fatalf("expected a read error and a duplicate : got\n%v", string(text))
+  }
+}
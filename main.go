@@ -6,18 +6,83 @@ This command supports standard in piping of a doctag file contents.
 If the `--output` argument is not specified then output will be 
 piped to standard out.
 
-  doctag {file path} | doctag [help|/?]
+Several files, directories (read recursively) and glob patterns can be
+given, the output is then a map of hierarchies keyed by the file paths
+relative to the working directory, or one hierarchy with `--merge`.
+With `--layers` the hierarchy of each file is merged over the hierarchies
+of the files before it (e.g. base content followed by regional overrides):
+maps are merged, values replaced and slices replaced or appended to
+(`--layer-slices`). Use `--sources` to write the file each value came from
+next to the value.
+Errors are reported for every file before exiting.
+
+With `--watch` the input files are polled for changes and the output is
+rewritten after every change, until interrupted. Errors are reported and
+the previous output is kept until the files are fixed. The output file is
+always replaced atomically, so readers never see a partly written file.
+
+The `from-json` command does the reverse: it flattens a JSON object
+into a doctag document, using '#' doctags for the items of arrays.
+
+The `gen-go` command generates a Go source file with struct types that
+mirror the hierarchy of a doctag file and a variable holding its values.
+It always transforms the doctags hierarchically, as with `--hierarchy`.
+
+  doctag {file, directory or pattern...} | doctag from-json {file path} | doctag gen-go {file path} | doctag lint {file, directory or pattern...} | doctag [help|/?]
+    -closers=false: Write a skipped doctag after each value (from-json only).
+    -conflicts="maps-win": What to do when a path is both a value and a map: error, warn, first-wins, last-wins or maps-win.
+    -diagnostics="text": The format of warnings and errors written to stderr: text or json.
+    -duplicate-separator="": The separator between duplicate values joined by -duplicates=concat.
+    -duplicates="last-wins": What to do when a path is assigned more than once: error, warn, first-wins, last-wins, concat or slice.
+    -format="json": The output format: json, yaml or toml.
+    -go-package="content": The package name of the generated Go source (gen-go only).
+    -go-type="Content": The struct type name of the generated Go source (gen-go only).
+    -go-var="Data": The variable name of the generated Go source, or empty for no variable (gen-go only).
     -help=false: Show the help message.
     -hierarchical=false: Converts the flat doctag tree into a nested JSON object.
     -hierarchy=false: Converts the flat doctag tree into a nested JSON object. (shorthand)
+    -include="*": The pattern of the file names read from input directories.
+    -layer-slices="replace": What to do with the slices of the layers merged by -layers: replace or append.
+    -layers=false: Merge the input files as layers, each file overriding the values of the files before it.
+    -lint-format="text": The format of the findings: text, json or sarif (lint only).
+    -merge=false: Merge the doctags of all input files into one hierarchy instead of a map keyed by file path.
+    -multiline-names=false: Allow doctag names to span several lines.
+    -no-skip=false: Disable skipping of doctags that start with the skip marker.
+    -ordered=false: Write keys in the order they appear in the document instead of sorted.
     -output="": The output file to write to.
     -pretty=false: Print JSON result with indentation. (shorthand)
     -pretty-print=false: Print JSON result with indentation.
+    -skip-marker="!": The prefix that marks doctags to skip. An empty marker disables skipping.
+    -sources=false: Write each value as a map of the value and the file, line and column it came from.
     -tag-prefix="<{": The prefix to use for doc tags.
     -tag-separator="/": The separator character to use for hierarchical doc tags.
     -tag-suffix="}>": The suffix to use for doc tags.
     -trim=false: Trim the leading and trailing whitespace from all doctag values.
+    -types="none": Convert values with a type attribute (annotated) or that look typed (inferred): none, annotated or inferred.
     -warn=false: Print warning messages.
+    -warnings-as-errors=false: Report warnings as errors and fail without writing the output.
+    -watch=false: Rewrite the output whenever the input files change, until interrupted.
+    -watch-interval=500ms: How often the input files are checked for changes by -watch.
+
+With `--no-skip` or another `--skip-marker`, closers such as `<{!}>` are
+kept as doctags named '!', and a warning is reported for each of them
+(even without `--warn`).
+
+Warnings (with `-warn`) and errors are written to stderr as
+"file:line:column: severity: message". When `-diagnostics=json` is used,
+each is written as a single line JSON object with the code, severity, file,
+line, column, offset and message of the diagnostic.
+
+The `lint` command checks doctag files for suspicious constructs: the
+warnings of the parser, duplicate paths, paths used both as a value and
+as a map, paths that are empty after converting to identifiers and names
+that differ only by whitespace or case. The findings are written as text,
+JSON or SARIF (`--lint-format`) and the exit code is 1 when there are any.
+
+The exit code is 0 on success, 2 for usage errors, 3 for parse errors,
+4 for transform errors (e.g. a '#' path or a conflict), 5 for I/O errors
+(e.g. a missing file) and 1 for lint findings and other errors. With `-warnings-as-errors`
+any warning fails the command with the exit code of its kind.
 */
 package main
 
@@ -25,34 +90,63 @@ import (
   "flag"
   "fmt"
   "os"
-  "log"
   "bufio"
   "bytes"
   "encoding/json"
   "strings"
+  "time"
+  "io/ioutil"
+  "path/filepath"
   "unicode/utf8"
   "github.com/dschnare/doctag/parse"
   "github.com/dschnare/doctag/identifier"
   "github.com/dschnare/doctag/hierarchy"
+  "github.com/dschnare/doctag/format"
+  "github.com/dschnare/doctag/generate"
 )
 
 var (
+  command string
   fileName string
+  inputs []string
   tagSeparator rune
   // Flags
   tagPrefix string
   tagSuffix string
   tagSeparatorStr string
+  skipMarker string
+  noSkip bool
+  multilineNames bool
+  diagnostics string
   output string
   help bool
   warn bool
+  warningsAsErrors bool
   prettyPrint bool
   hierarchical bool
   trim bool
+  closers bool
+  outputFormat string
+  lintFormat string
+  goPackage string
+  goType string
+  goVar string
+  types string
+  conflicts string
+  duplicates string
+  duplicateSeparator string
+  ordered bool
+  merge bool
+  include string
+  layers bool
+  layerSlices string
+  sources bool
+  watch bool
+  watchInterval time.Duration
 )
 
 func usage() {
-  fmt.Fprintf(os.Stderr, "Usage: doctag {file path} | doctag [help|/?]\n")
+  fmt.Fprintf(os.Stderr, "Usage: doctag {file, directory or pattern...} | doctag from-json {file path} | doctag gen-go {file path} | doctag lint {file, directory or pattern...} | doctag [help|/?]\n")
   flag.PrintDefaults()
 }
 
@@ -64,6 +158,8 @@ func init() {
     prettyPrintUsage = "Print JSON result with indentation."
     warnDefault = false
     warnUsage = "Print warning messages."
+    warningsAsErrorsDefault = false
+    warningsAsErrorsUsage = "Report warnings as errors and fail without writing the output."
     hierarchicalDefault = false
     hierarchicalUsage = "Converts the flat doctag tree into a nested JSON object."
     trimDefault = false
@@ -74,6 +170,50 @@ func init() {
     tagSuffixUsage = "The suffix to use for doc tags."
     tagSeparatorDefault = string(hierarchy.DefaultSeparator)
     tagSeparatorUsage = "The separator character to use for hierarchical doc tags."
+    skipMarkerDefault = parse.DefaultSkipMarker
+    skipMarkerUsage = "The prefix that marks doctags to skip. An empty marker disables skipping."
+    multilineNamesDefault = false
+    multilineNamesUsage = "Allow doctag names to span several lines."
+    noSkipDefault = false
+    noSkipUsage = "Disable skipping of doctags that start with the skip marker."
+    diagnosticsDefault = "text"
+    diagnosticsUsage = "The format of warnings and errors written to stderr: text or json."
+    closersDefault = false
+    closersUsage = "Write a skipped doctag after each value (from-json only)."
+    lintFormatDefault = "text"
+    lintFormatUsage = "The format of the findings: text, json or sarif (lint only)."
+    outputFormatDefault = "json"
+    outputFormatUsage = "The output format: json, yaml or toml."
+    goPackageDefault = generate.DefaultGoPackage
+    goPackageUsage = "The package name of the generated Go source (gen-go only)."
+    goTypeDefault = generate.DefaultGoType
+    goTypeUsage = "The struct type name of the generated Go source (gen-go only)."
+    goVarDefault = generate.DefaultGoVar
+    goVarUsage = "The variable name of the generated Go source, or empty for no variable (gen-go only)."
+    conflictsDefault = "maps-win"
+    conflictsUsage = "What to do when a path is both a value and a map: error, warn, first-wins, last-wins or maps-win."
+    duplicatesDefault = "last-wins"
+    duplicatesUsage = "What to do when a path is assigned more than once: error, warn, first-wins, last-wins, concat or slice."
+    duplicateSeparatorDefault = ""
+    duplicateSeparatorUsage = "The separator between duplicate values joined by -duplicates=concat."
+    typesDefault = "none"
+    typesUsage = "Convert values with a type attribute (annotated) or that look typed (inferred): none, annotated or inferred."
+    orderedDefault = false
+    orderedUsage = "Write keys in the order they appear in the document instead of sorted."
+    mergeDefault = false
+    mergeUsage = "Merge the doctags of all input files into one hierarchy instead of a map keyed by file path."
+    layersDefault = false
+    layersUsage = "Merge the input files as layers, each file overriding the values of the files before it."
+    layerSlicesDefault = "replace"
+    layerSlicesUsage = "What to do with the slices of the layers merged by -layers: replace or append."
+    sourcesDefault = false
+    sourcesUsage = "Write each value as a map of the value and the file, line and column it came from."
+    watchDefault = false
+    watchUsage = "Rewrite the output whenever the input files change, until interrupted."
+    watchIntervalDefault = 500 * time.Millisecond
+    watchIntervalUsage = "How often the input files are checked for changes by -watch."
+    includeDefault = "*"
+    includeUsage = "The pattern of the file names read from input directories."
     outputDefault = ""
     outputUsage = "The output file to write to."
   )
@@ -86,26 +226,71 @@ func init() {
   flag.BoolVar(&prettyPrint, "pretty", prettyPrintDefault, prettyPrintUsage + " (shorthand)")
 
   flag.BoolVar(&warn, "warn", warnDefault, warnUsage)
+  flag.BoolVar(&warningsAsErrors, "warnings-as-errors", warningsAsErrorsDefault, warningsAsErrorsUsage)
 
   flag.BoolVar(&hierarchical, "hierarchical", hierarchicalDefault, hierarchicalUsage)
   flag.BoolVar(&hierarchical, "hierarchy", hierarchicalDefault, hierarchicalUsage + " (shorthand)")
 
   flag.BoolVar(&trim, "trim", trimDefault, trimUsage)
 
+  flag.StringVar(&types, "types", typesDefault, typesUsage)
+
+  flag.StringVar(&conflicts, "conflicts", conflictsDefault, conflictsUsage)
+
+  flag.StringVar(&duplicates, "duplicates", duplicatesDefault, duplicatesUsage)
+  flag.StringVar(&duplicateSeparator, "duplicate-separator", duplicateSeparatorDefault, duplicateSeparatorUsage)
+
   flag.StringVar(&tagPrefix, "tag-prefix", tagPrefixDefault, tagPrefixUsage)
 
   flag.StringVar(&tagSuffix, "tag-suffix", tagSuffixDefault, tagSuffixUsage)
 
   flag.StringVar(&tagSeparatorStr, "tag-separator", tagSeparatorDefault, tagSeparatorUsage)
 
+  flag.StringVar(&skipMarker, "skip-marker", skipMarkerDefault, skipMarkerUsage)
+  flag.BoolVar(&noSkip, "no-skip", noSkipDefault, noSkipUsage)
+
+  flag.BoolVar(&multilineNames, "multiline-names", multilineNamesDefault, multilineNamesUsage)
+
   flag.StringVar(&output, "output", outputDefault, outputUsage)
 
-  flag.Parse()
+  flag.BoolVar(&ordered, "ordered", orderedDefault, orderedUsage)
+
+  flag.BoolVar(&merge, "merge", mergeDefault, mergeUsage)
+  flag.StringVar(&include, "include", includeDefault, includeUsage)
+  flag.BoolVar(&layers, "layers", layersDefault, layersUsage)
+  flag.StringVar(&layerSlices, "layer-slices", layerSlicesDefault, layerSlicesUsage)
 
+  flag.BoolVar(&sources, "sources", sourcesDefault, sourcesUsage)
+
+  flag.BoolVar(&watch, "watch", watchDefault, watchUsage)
+  flag.DurationVar(&watchInterval, "watch-interval", watchIntervalDefault, watchIntervalUsage)
+
+  flag.StringVar(&diagnostics, "diagnostics", diagnosticsDefault, diagnosticsUsage)
+
+  flag.BoolVar(&closers, "closers", closersDefault, closersUsage)
+
+  flag.StringVar(&outputFormat, "format", outputFormatDefault, outputFormatUsage)
+  flag.StringVar(&lintFormat, "lint-format", lintFormatDefault, lintFormatUsage)
+
+  flag.StringVar(&goPackage, "go-package", goPackageDefault, goPackageUsage)
+  flag.StringVar(&goType, "go-type", goTypeDefault, goTypeUsage)
+  flag.StringVar(&goVar, "go-var", goVarDefault, goVarUsage)
+}
 
+// Parses the command line arguments (without the program name) into the flags, the command and its inputs.
+func parseArgs(args []string) {
+  command,fileName,inputs = "","",nil
+  flag.CommandLine.Parse(args)
 
-  if warn {
-    parse.Logger = log.New(os.Stderr, "doctag warning: ", log.Lshortfile)
+  // Commands are followed by their own flags.
+  if flag.NArg() > 0 && (flag.Arg(0) == "from-json" || flag.Arg(0) == "gen-go" || flag.Arg(0) == "lint") {
+    command = flag.Arg(0)
+    flag.CommandLine.Parse(flag.Args()[1:])
+  }
+
+  // Generated Go types always mirror the hierarchy.
+  if command == "gen-go" {
+    hierarchical = true
   }
 
   if len(tagSeparatorStr) == 0 {
@@ -117,41 +302,139 @@ func init() {
   if help {
     flag.Usage()
     os.Exit(0)
+  } else if diagnostics != "text" && diagnostics != "json" {
+    usageError("-diagnostics must be text or json")
+  } else if outputFormat != "json" && outputFormat != "yaml" && outputFormat != "toml" {
+    usageError("-format must be json, yaml or toml")
+  } else if lintFormat != "text" && lintFormat != "json" && lintFormat != "sarif" {
+    usageError("-lint-format must be text, json or sarif")
+  } else if types != "none" && types != "annotated" && types != "inferred" {
+    usageError("-types must be none, annotated or inferred")
+  } else if !strings.Contains(" error warn first-wins last-wins maps-win ", " " + conflicts + " ") {
+    usageError("-conflicts must be error, warn, first-wins, last-wins or maps-win")
+  } else if !strings.Contains(" error warn first-wins last-wins concat slice ", " " + duplicates + " ") {
+    usageError("-duplicates must be error, warn, first-wins, last-wins, concat or slice")
   } else if len(flag.Args()) == 1 && (flag.Arg(0) == "/?" || flag.Arg(0) == "help") {
     flag.Usage()
     os.Exit(0)
-  } else if len(flag.Args()) == 1 {
+  } else if layerSlices != "replace" && layerSlices != "append" {
+    usageError("-layer-slices must be replace or append")
+  } else if layers && merge {
+    usageError("-layers and -merge cannot be used together")
+  } else if _,err := filepath.Match(include, ""); err != nil {
+    usageError("-include is not a valid pattern")
+  } else if len(flag.Args()) == 1 && !isMultiInput(flag.Args()) {
     fileName = flag.Arg(0)
+  } else if len(flag.Args()) > 0 && (len(command) == 0 || command == "lint") {
+    // Several files, directories or glob patterns.
+    inputs = flag.Args()
+  } else if len(flag.Args()) == 0 && len(command) > 0 && isPiped(os.Stdin) {
+    // Commands read standard in without a file path.
+  } else if len(flag.Args()) == 0 && !isPiped(os.Stdin) {
+    usageError("no input file")
+  } else if len(flag.Args()) > 0 {
+    usageError(command + " reads a single file")
   } else {
     flag.Usage()
-    os.Exit(1)
+    os.Exit(exitUsage)
+  }
+
+  // Only files can be watched.
+  if watch && watchInterval <= 0 {
+    usageError("-watch-interval must be positive")
+  } else if watch && len(fileName) == 0 && len(inputs) == 0 {
+    usageError("-watch needs input files")
   }
 }
 
 func main() {
-  if doctags,err := doParse(); err == nil {
-    if writer,err := createWriter(); err == nil {
-      if err := doWrite(writer, doctags); err != nil {
-        panic(err)
-      }
-    } else {
-      panic(err)
+  parseArgs(os.Args[1:])
+
+  if watch {
+    doWatch()
+    return
+  }
+
+  if err := run(); err != nil {
+    fail(err)
+  } else if findings > 0 {
+    os.Exit(exitError)
+  }
+}
+
+// Runs the command once and writes its output. Nothing is written when there's an error.
+func run() (err error) {
+  outputBuffer.Reset()
+  reportedCode = 0
+  warnings = 0
+
+  if command == "from-json" {
+    err = doFromJSON()
+  } else if command == "lint" {
+    err = doLint()
+  } else if command == "gen-go" {
+    err = doGenGo()
+  } else if len(inputs) > 0 {
+    err = doInputs()
+  } else if doctags,parseErr := doParse(); parseErr == nil {
+    var writer *bufio.Writer
+    if writer,err = createWriter(); err == nil {
+      err = doWrite(writer, doctags)
     }
   } else {
-    panic(err)
+    err = parseErr
+  }
+
+  if err == nil && warningsAsErrors && warnings > 0 {
+    err = errReported
   }
+  if err == nil {
+    err = commitOutput()
+  }
+
+  return
 }
 
 func doParse() (doctags []*parse.DoctagNode, err error) {
+  parser := newParser()
+
   if isPiped(os.Stdin) {
-    doctags,err = parse.ParseWithPrefixAndSuffix(bufio.NewReader(os.Stdin), tagPrefix, tagSuffix)
+    doctags,err = parser.Parse(os.Stdin)
   } else {
-    doctags,err = parse.ParseFileWithPrefixAndSuffix(fileName, tagPrefix, tagSuffix)
+    doctags,err = parser.ParseFile(fileName)
   }
 
   return
 }
 
+func newParser() *parse.Parser {
+  parser := parse.NewParser()
+  parser.TagPrefix = tagPrefix
+  parser.TagSuffix = tagSuffix
+  parser.SkipMarker = skipMarker
+  parser.MultilineNames = multilineNames
+
+  if noSkip {
+    parser.SkipMarker = ""
+  }
+
+  if warn || warningsAsErrors {
+    parser.WarningHandler = reportWarning
+  } else {
+    // Closers kept as doctags named '!' (with -no-skip or another -skip-marker) are reported even without -warn.
+    parser.WarningHandler = func (warning *parse.Diagnostic) {
+      if warning.Code == parse.CodeUnskippedCloser {
+        reportWarning(warning)
+      }
+    }
+  }
+  if trim {
+    parser.Trim = parse.TrimSpace
+  }
+
+  return parser
+}
+
 func isPiped(file *os.File) bool {
   if info,err := file.Stat(); err == nil {
     return info.Mode() == os.ModeNamedPipe
@@ -159,41 +442,198 @@ func isPiped(file *os.File) bool {
   return false
 }
 
+// The output is buffered until the command succeeds, so that the output file is never left partly written.
+var outputBuffer bytes.Buffer
+
 func createWriter() (*bufio.Writer, error) {
-  var writer *bufio.Writer
+  outputBuffer.Reset()
+  return bufio.NewWriter(&outputBuffer),nil
+}
 
+// Writes the buffered output to standard out, or replaces the output file with it.
+func commitOutput() error {
   if len(output) == 0 || isPiped(os.Stdout) {
-    writer = bufio.NewWriter(os.Stdout)
-  } else if file,err := os.Create(output); err == nil {
-    writer = bufio.NewWriter(file)
-  } else {
-    return nil,err
+    _,err := outputBuffer.WriteTo(os.Stdout)
+    return err
+  }
+  return writeFileAtomic(output, outputBuffer.Bytes())
+}
+
+// Replaces a file with data by writing a temporary file in the same directory and renaming it,
+// so that readers of the file never see it partly written.
+func writeFileAtomic(name string, data []byte) error {
+  mode := os.FileMode(0644)
+  if info,err := os.Stat(name); err == nil {
+    mode = info.Mode().Perm()
+  }
+
+  file,err := ioutil.TempFile(filepath.Dir(name), "." + filepath.Base(name) + ".*.tmp")
+  if err != nil {
+    return err
+  }
+
+  _,err = file.Write(data)
+  if err == nil {
+    err = file.Chmod(mode)
+  }
+  if closeErr := file.Close(); err == nil {
+    err = closeErr
+  }
+  if err == nil {
+    err = os.Rename(file.Name(), name)
+  }
+  if err != nil {
+    os.Remove(file.Name())
   }
 
-  return writer,nil
+  return err
 }
 
-func doWrite(writer *bufio.Writer, doctags []*parse.DoctagNode) (err error) {
-  var (
-    b []byte
-    value interface{}
-  )
+// Transforms the doctags into the map that is written, hierarchical or flat.
+func doTransform(doctags []*parse.DoctagNode) (map[string]interface{}, error) {
+  return newTransformer(doctags).Transform(doctags)
+}
 
+// Creates a transformer configured by the flags for the doctags.
+func newTransformer(doctags []*parse.DoctagNode) *hierarchy.Transformer {
   for _,doctag := range doctags {
     if !hierarchical {
       // This will remove the separator characters and convert JSON keys to identifiers.
       doctag.Name = identifier.ToGoIdentifier(strings.Replace(doctag.Name, string(tagSeparator), "_", -1))
     }
-    if trim {
-      doctag.Value = strings.TrimSpace(doctag.Value)
+  }
+
+  transformer := hierarchy.NewTransformer()
+  transformer.Separator = tagSeparator
+  transformer.KeysToIdentifiers = hierarchical
+
+  transformer.Types = typeMode()
+
+  switch conflicts {
+  case "error":
+    transformer.Conflicts = hierarchy.ConflictError
+  case "warn":
+    transformer.Conflicts = hierarchy.ConflictWarn
+  case "first-wins":
+    transformer.Conflicts = hierarchy.ConflictFirstWins
+  case "last-wins":
+    transformer.Conflicts = hierarchy.ConflictLastWins
+  }
+
+  switch duplicates {
+  case "error":
+    transformer.Duplicates = hierarchy.DuplicateError
+  case "warn":
+    transformer.Duplicates = hierarchy.DuplicateWarn
+  case "first-wins":
+    transformer.Duplicates = hierarchy.DuplicateFirstWins
+  case "concat":
+    transformer.Duplicates = hierarchy.DuplicateConcat
+  case "slice":
+    transformer.Duplicates = hierarchy.DuplicateSlice
+  }
+  transformer.DuplicateSeparator = duplicateSeparator
+
+  // Warnings are only reported by the transformer when a policy asks for them.
+  transformer.WarningHandler = reportWarning
+
+  return transformer
+}
+
+// Returns the type mode of the -types flag.
+func typeMode() hierarchy.TypeMode {
+  if types == "annotated" {
+    return hierarchy.TypesAnnotated
+  } else if types == "inferred" {
+    return hierarchy.TypesInferred
+  }
+  return hierarchy.TypesNone
+}
+
+func doWrite(writer *bufio.Writer, doctags []*parse.DoctagNode) error {
+  value,err := transformValue(doctags)
+  if err != nil {
+    return err
+  }
+  return writeValue(writer, value)
+}
+
+// Transforms the doctags into the value that is written.
+func transformValue(doctags []*parse.DoctagNode) (interface{}, error) {
+  root,err := newTransformer(doctags).TransformTree(doctags)
+  if err != nil {
+    return nil,err
+  }
+  return treeValue(root),nil
+}
+
+// Returns the value written for a transformed tree, with ordered maps when -ordered is used
+// and the sources of the values when -sources is used.
+func treeValue(root *hierarchy.Node) interface{} {
+  if sources {
+    root = sourceTree(root)
+  }
+  if ordered {
+    return root.Ordered()
+  }
+  return root.Interface()
+}
+
+// Returns a copy of the tree n with each value replaced by a map of the value and its source.
+// The tree is copied since merged trees share their nodes with the layers they were merged from.
+func sourceTree(n *hierarchy.Node) *hierarchy.Node {
+  c := *n
+
+  switch n.Kind {
+  case hierarchy.MapNode:
+    c.Children = make(map[string]*hierarchy.Node, len(n.Children))
+    for key,child := range n.Children {
+      c.Children[key] = sourceTree(child)
+    }
+  case hierarchy.ListNode:
+    c.Items = make([]*hierarchy.Node, len(n.Items))
+    for k,item := range n.Items {
+      c.Items[k] = sourceTree(item)
+    }
+  default:
+    return &hierarchy.Node{
+      Kind: hierarchy.MapNode,
+      Keys: []string{"value", "source"},
+      Children: map[string]*hierarchy.Node{
+        "value": n,
+        "source": &hierarchy.Node{Kind: hierarchy.LeafNode, Value: source(n.Doctag()), Doctags: n.Doctags},
+      },
+      Doctags: n.Doctags,
     }
   }
 
-  if value,err = hierarchy.TransformWithSeparator(doctags, hierarchical, tagSeparator); err != nil {
-    return
+  return &c
+}
+
+// Describes where a doctag is as "file:line:column", or "line:column" for standard in.
+// Values that were not set by a doctag have no source.
+func source(doctag *parse.DoctagNode) string {
+  if doctag == nil {
+    return ""
+  } else if len(doctag.File) > 0 {
+    return fmt.Sprintf("%v:%v:%v", relativePath(doctag.File), doctag.Line, doctag.Column)
   }
+  return fmt.Sprintf("%v:%v", doctag.Line, doctag.Column)
+}
 
-  if prettyPrint {
+// Writes the value in the output format.
+func writeValue(writer *bufio.Writer, value interface{}) (err error) {
+  var b []byte
+
+  if outputFormat == "yaml" {
+    if err = format.WriteYAML(writer, value); err == nil {
+      err = writer.Flush()
+    }
+  } else if outputFormat == "toml" {
+    if err = format.WriteTOML(writer, value); err == nil {
+      err = writer.Flush()
+    }
+  } else if prettyPrint {
     if b,err = json.Marshal(value); err == nil {
       var out bytes.Buffer
       if err = json.Indent(&out, b, "", "  "); err == nil {
@@ -0,0 +1,74 @@
+package main
+
+import (
+  "testing"
+  "reflect"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "github.com/dschnare/doctag/parse"
+  "github.com/dschnare/doctag/hierarchy"
+)
+
+func TestTreeValue_Sources(t *testing.T) {
+  transformer := hierarchy.NewTransformer()
+  base,err := transformer.TransformTree([]*parse.DoctagNode{
+    &parse.DoctagNode{Name: "page/title", Value: "Title", Line: 1, Column: 1},
+    &parse.DoctagNode{Name: "page/#tags", Value: "a", Line: 2, Column: 1},
+  })
+  if err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+  region,err := transformer.TransformTree([]*parse.DoctagNode{
+    &parse.DoctagNode{Name: "page/title", Value: "Titre", Line: 3, Column: 2},
+  })
+  if err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+
+  sources = true
+  defer func () { sources = false }()
+
+  value := treeValue(hierarchy.Merge([]*hierarchy.Node{base, region}, hierarchy.SlicesReplace))
+  expected := map[string]interface{}{
+    "page": map[string]interface{}{
+      "title": map[string]interface{}{"value": "Titre", "source": "3:2"},
+      "tags": []interface{}{map[string]interface{}{"value": "a", "source": "2:1"}},
+    },
+  }
+  if !reflect.DeepEqual(value, expected) {
+    t.Fatalf("expected %v : got %v", expected, value)
+  }
+
+  // The layers share their nodes with the merged tree and are left as they were.
+  if title := base.Find("page/title", hierarchy.DefaultSeparator); title.Value != "Title" {
+    t.Fatalf("expected the base title to be 'Title' : got %v", title.Value)
+  }
+  if tag := base.Find("page/tags[0]", hierarchy.DefaultSeparator); tag.Value != "a" {
+    t.Fatalf("expected the base tag to be 'a' : got %v", tag.Value)
+  }
+}
+
+func TestRun_UnskippedCloser(t *testing.T) {
+  dir,err := ioutil.TempDir("", "doctag")
+  if err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+  defer os.RemoveAll(dir)
+
+  input := filepath.Join(dir, "content.txt")
+  out := filepath.Join(dir, "content.json")
+  if err = ioutil.WriteFile(input, []byte("<{ !important }>a<{!}>"), 0644); err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+
+  // The closer is reported without -warn.
+  parseArgs([]string{"-hierarchy=false", "-no-skip", "-output=" + out, input})
+  defer func () { noSkip = false }()
+  if err = run(); err != nil {
+    t.Fatalf("unexpected error encountered : %v", err.Error())
+  }
+  if warnings != 1 {
+    t.Fatalf("expected 1 warning : got %v", warnings)
+  }
+}
@@ -0,0 +1,97 @@
+package parse
+
+import (
+  "strings"
+  "unicode"
+)
+
+// SplitAttributes splits the text of a doctag into its name and attributes.
+// Attributes are whitespace separated key=value pairs that follow the name.
+// The first field of the text always belongs to the name, and the name ends
+// at the first following field that contains a '=' character. Attribute values
+// can be quoted with '"' to include whitespace. Fields without a '=' character
+// in the attribute list are attributes with an empty value.
+// The returned attributes are nil when text has no attributes.
+func SplitAttributes(text string) (name string, attributes map[string]string) {
+  start := attributesStart(text)
+
+  if start < 0 {
+    return text,nil
+  }
+
+  name = strings.TrimSpace(text[:start])
+  attributes = make(map[string]string)
+  rest := text[start:]
+
+  for {
+    rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+    if len(rest) == 0 {
+      break
+    }
+
+    end := strings.IndexFunc(rest, func (r rune) bool {
+      return r == '=' || unicode.IsSpace(r)
+    })
+    if end < 0 {
+      attributes[rest] = ""
+      break
+    }
+
+    key := rest[:end]
+    rest = rest[end:]
+
+    if rest[0] != '=' {
+      attributes[key] = ""
+      continue
+    }
+
+    var value string
+    value,rest = attributeValue(rest[1:])
+    if len(key) > 0 {
+      attributes[key] = value
+    }
+  }
+
+  return
+}
+
+// Returns the byte index of the first attribute in text or -1 if text has no attributes.
+func attributesStart(text string) int {
+  inName := false
+  fieldStart := -1
+
+  for k,r := range text {
+    if unicode.IsSpace(r) {
+      if fieldStart >= 0 {
+        inName = true
+      }
+      fieldStart = -1
+      continue
+    }
+
+    if fieldStart < 0 {
+      fieldStart = k
+    }
+    if r == '=' && inName && fieldStart < k {
+      return fieldStart
+    }
+  }
+
+  return -1
+}
+
+// Reads an attribute value (quoted or not) from the start of text and returns it with the remaining text.
+func attributeValue(text string) (value string, rest string) {
+  if strings.HasPrefix(text, `"`) {
+    if end := strings.IndexRune(text[1:], '"'); end >= 0 {
+      return text[1:end + 1], text[end + 2:]
+    }
+    return text[1:], ""
+  }
+
+  if end := strings.IndexFunc(text, unicode.IsSpace); end >= 0 {
+    return text[:end], text[end:]
+  }
+
+  return text,""
+}
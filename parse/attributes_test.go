@@ -0,0 +1,48 @@
+package parse
+
+import (
+  "testing"
+  "reflect"
+  "strings"
+)
+
+func TestSplitAttributes(t *testing.T) {
+  tests := []struct {
+    text string
+    name string
+    attributes map[string]string
+  }{
+    {"page/title", "page/title", nil},
+    {"Headline4 / Link", "Headline4 / Link", nil},
+    {"a=b", "a=b", nil},
+    {"page/title lang=en format=markdown", "page/title", map[string]string{"lang": "en", "format": "markdown"}},
+    {"page / title lang=en", "page / title", map[string]string{"lang": "en"}},
+    {`page note="needs review" draft x=`, "page", map[string]string{"note": "needs review", "draft": "", "x": ""}},
+    {"page x=1 =2", "page", map[string]string{"x": "1"}},
+    {`page note="unterminated value`, "page", map[string]string{"note": "unterminated value"}},
+  }
+
+  for _,test := range tests {
+    name,attributes := SplitAttributes(test.text)
+    if name != test.name {
+      t.Fatalf("expected name '%v' for '%v' : got '%v'", test.name, test.text, name)
+    }
+    if !reflect.DeepEqual(attributes, test.attributes) {
+      t.Fatalf("expected attributes %v for '%v' : got %v", test.attributes, test.text, attributes)
+    }
+  }
+}
+
+func TestParser_Attributes(t *testing.T) {
+  doctags,err := NewParser().Parse(strings.NewReader("<{ page/title lang=en }>Title<{ page/body }>Body"))
+
+  if err != nil {
+    t.Fatalf("expected no error: %v", err.Error())
+  }
+  if len(doctags) != 2 || doctags[0].Name != "page/title" || doctags[0].Attributes["lang"] != "en" {
+    t.Fatalf("expected doctag 'page/title' with lang=en : got %v", doctags[0])
+  }
+  if doctags[1].Attributes != nil {
+    t.Fatalf("expected no attributes : got %v", doctags[1].Attributes)
+  }
+}
@@ -0,0 +1,102 @@
+package parse
+
+import (
+  "fmt"
+)
+
+// Severity indicates whether a Diagnostic stops parsing (an error) or not (a warning).
+type Severity int
+
+// The severities of a Diagnostic.
+const (
+  SeverityWarning Severity = iota
+  SeverityError
+)
+
+// String returns "warning" or "error".
+func (severity Severity) String() string {
+  if severity == SeverityError {
+    return "error"
+  }
+  return "warning"
+}
+
+// MarshalText encodes the severity as its string form, which is also used when encoding to JSON.
+func (severity Severity) MarshalText() ([]byte, error) {
+  return []byte(severity.String()), nil
+}
+
+// UnmarshalText decodes a severity from its string form.
+func (severity *Severity) UnmarshalText(text []byte) error {
+  switch string(text) {
+  case "warning":
+    *severity = SeverityWarning
+  case "error":
+    *severity = SeverityError
+  default:
+    return fmt.Errorf("unknown severity '%v'", string(text))
+  }
+  return nil
+}
+
+// Code identifies the kind of problem a Diagnostic describes.
+type Code string
+
+// The codes of the diagnostics reported by a Parser.
+const (
+  // CodeInvalidConfig reports a Parser configuration that cannot be used.
+  CodeInvalidConfig Code = "invalid-config"
+  // CodeRead reports an error returned by the underlying reader.
+  CodeRead Code = "read-error"
+  // CodeUnclosedTag reports a doctag open encountered while the previous doctag has no name.
+  CodeUnclosedTag Code = "unclosed-tag"
+  // CodeStraySuffix reports a doctag close encountered while not reading a doctag name.
+  CodeStraySuffix Code = "stray-suffix"
+  // CodeEmptyName reports a doctag without a name.
+  CodeEmptyName Code = "empty-name"
+  // CodeSkippedTag reports a doctag that was skipped because of the skip marker.
+  CodeSkippedTag Code = "skipped-tag"
+  // CodeUnskippedCloser reports a doctag named the default skip marker (i.e. the closer <{!}>)
+  // that is kept because skipping is disabled or uses another marker.
+  CodeUnskippedCloser Code = "unskipped-closer"
+  // CodeMultilineName reports a doctag name that was abandoned because it continues onto the next line.
+  CodeMultilineName Code = "multiline-name"
+  // CodeNameTooLong reports a doctag name longer than Parser.MaxNameLength.
+  CodeNameTooLong Code = "name-too-long"
+  // CodeValueTooLong reports a doctag value longer than Parser.MaxValueLength.
+  CodeValueTooLong Code = "value-too-long"
+  // CodeTooManyNodes reports a document with more doctags than Parser.MaxNodes.
+  CodeTooManyNodes Code = "too-many-nodes"
+)
+
+// A Diagnostic describes a problem found in a document along with its location.
+// Errors returned while parsing are *Diagnostic values and can be inspected with errors.As.
+// Line and Column are 1-based (a Column of 0 refers to the line break ending the previous line),
+// Offset is the 0-based byte offset into the document. The location is zero when unknown.
+type Diagnostic struct {
+  Code Code `json:"code"`
+  Severity Severity `json:"severity"`
+  File string `json:"file,omitempty"`
+  Line int `json:"line"`
+  Column int `json:"column"`
+  Offset int `json:"offset"`
+  Message string `json:"message"`
+  // Err is the underlying error, if any (e.g. the error returned by the reader).
+  Err error `json:"-"`
+}
+
+// Error formats the diagnostic with its location.
+func (d *Diagnostic) Error() string {
+  if d.Line == 0 {
+    return d.Message
+  }
+  if len(d.File) > 0 {
+    return fmt.Sprintf("%v: Line: %v, Column: %v :: %v", d.File, d.Line, d.Column, d.Message)
+  }
+  return fmt.Sprintf("Line: %v, Column: %v :: %v", d.Line, d.Column, d.Message)
+}
+
+// Unwrap returns the underlying error, if any.
+func (d *Diagnostic) Unwrap() error {
+  return d.Err
+}
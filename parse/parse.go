@@ -1,5 +1,8 @@
 /*
 Package parse builds a slice of nodes from UTF-8 encoded text documents that have doctags.
+Large documents can be read one node at a time with a Scanner.
+A Parser carries the configuration (prefix, suffix, logger, trimming policy
+and limits) so that differently configured parsers can run concurrently.
 
 Doctags are simple tags that can be written in any text document used to indicate
 a named or tagged piece of content. An example document with a doctag could be:
@@ -41,40 +44,98 @@ over leading and trailing whitespace and/or improving ledgability.
   <{ page/content }>
   Blah ablah blab ablaha bal.
   <{!}>
+
+Doctags can have attributes, which are whitespace separated key=value pairs
+following the name. Values containing whitespace can be quoted with '"', and keys
+without a value have an empty value. The name ends at the first attribute.
+
+  <{ page/title lang=en format=markdown note="needs review" }>
+
+Results in a DoctagNode with the name "page/title" and the attributes
+lang="en", format="markdown" and note="needs review". The first field of a
+doctag is always part of the name, so <{ a=b }> has the name "a=b".
+
+Doctag names must be on a single line unless the MultilineNames field of a Parser
+is set, in which case long names may be wrapped and the whitespace between the
+wrapped parts is collapsed to a single space. A line break next to punctuation,
+such as the separator of a hierarchical name, is removed along with the
+whitespace around it, so the name below is "page/sections/introduction/title".
+
+  <{ page/sections/introduction/
+     title }>
+
+A literal tag prefix can be written inside a doctag value by doubling it.
+The doubled prefix is replaced by a single prefix in the DoctagNode value.
+
+  <{ code/example }>Write <{<{ page/title }> to start a title.
+
+Results in the value "Write <{ page/title }> to start a title.". Note that
+the tag suffix never needs to be escaped in a value. Writers can use
+EscapeValue to escape values before writing them after a doctag.
+
+The skip marker is configurable through the SkipMarker field of a Parser.
+Setting it to the empty string disables skipping entirely, so that doctags
+such as <{ !important }> are kept. Note that closers such as <{!}> are then
+kept as well, as doctags named "!" (the same goes for a custom marker).
+A CodeUnskippedCloser warning is reported for each of them.
 */
 package parse
 
 import (
-  "os"
-  "io"
-  "fmt"
   "bufio"
   "log"
   "strings"
-  "errors"
+  "path/filepath"
   "unicode/utf8"
 )
 
-// The default tag prefix and suffix used by the Parse() function.
+// The default tag prefix, suffix and skip marker used by the Parse() function.
 const (
   DefaultTagPrefix = "<{"
   DefaultTagSuffix = "}>"
+  DefaultSkipMarker = "!"
 )
 
 // The optional Logger to have warnings logged to. The logger is useful
 // in finding what might be typos when declaring a doctag in a document.
+// Logger is only used by the package level functions; use the Logger
+// field of a Parser to log warnings from concurrent parsers.
 var (
   Logger *log.Logger
 )
 
 // A DoctagNode represents a doctag parsed from a text document.
+// Line and Column locate the start of the doctag. Tag is the region of the
+// document covered by the doctag itself (from its prefix through its suffix)
+// and ValueSpan is the region covered by its value. ValueSpan covers the value
+// as written in the document, before escaped prefixes are unescaped and before
+// the value is trimmed. Attributes is nil when the doctag has no attributes.
+// File is the name of the file the doctag was parsed from, empty when parsed from a reader.
 type DoctagNode struct {
   Name string
   Value string
+  Attributes map[string]string
+  Line int
+  Column int
+  Tag Span
+  ValueSpan Span
+  File string
+}
+
+// A Position is a location in a document. Offset is the 0-based byte offset,
+// Line and Column are the 1-based line and rune column of the byte at Offset.
+type Position struct {
+  Offset int
   Line int
   Column int
 }
 
+// A Span is the region of a document from Start up to, but not including, End.
+type Span struct {
+  Start Position
+  End Position
+}
+
 // Parse parses a text file for doctags using the default prefix and suffix substrings.
 // The returned slice contains all parsed DoctagNodes in the order they appear in the document.
 func ParseFile(fileName string) ([]*DoctagNode, error) {
@@ -84,14 +145,7 @@ func ParseFile(fileName string) ([]*DoctagNode, error) {
 // ParseFileWithPrefixAndSuffix parses a text file for doctags using custom prefix and suffix substrings for doctags.
 // The returned slice contains all parsed DoctagNodes in the order they appear in the document.
 func ParseFileWithPrefixAndSuffix(fileName string, tagPrefix string, tagSuffix string) ([]*DoctagNode, error) {
-  file,err := os.Open(fileName)
-  defer file.Close()
-
-  if err == nil {
-    return ParseWithPrefixAndSuffix(bufio.NewReader(file), tagPrefix, tagSuffix)
-  }
-
-  return nil,err
+  return defaultParser(tagPrefix, tagSuffix).ParseFile(fileName)
 }
 
 // Parse parses a buffered reader for doctags using the default prefix and suffix substrings.
@@ -102,103 +156,25 @@ func Parse(reader *bufio.Reader) ([]*DoctagNode, error) {
 
 // ParseWithPrefixAndSuffix parses a buffered reader for doctags using custom prefix and suffix substrings for doctags.
 // The returned slice contains all parsed DoctagNodes in the order they appear in the document.
-func ParseWithPrefixAndSuffix(reader *bufio.Reader, tagPrefix string, tagSuffix string) (doctags []*DoctagNode, err error) {
-  if tagPrefix == tagSuffix {
-    err = errors.New("Tag prefix and suffix cannot be the same.")
-    return
-  }
-  if len(tagPrefix) == 0 {
-    err = errors.New("Tag prefix cannot be the empty string.")
-    return
-  }
-  if len(tagSuffix) == 0 {
-    err = errors.New("Tag suffix cannot be the empty string.")
-    return
-  }
+func ParseWithPrefixAndSuffix(reader *bufio.Reader, tagPrefix string, tagSuffix string) ([]*DoctagNode, error) {
+  return defaultParser(tagPrefix, tagSuffix).Parse(reader)
+}
 
-  // The capacity to create text buffers at (i.e. to capture text between doctags).
-  const bufferSize = 512
-  doctags = make([]*DoctagNode, 0, 50)
-  buff := make([]byte, 0, bufferSize)
-  line := 1
-  column := 0
-  var currTag *DoctagNode
-  var b byte
-
-  for b,err = reader.ReadByte(); err == nil || err == io.EOF; b,err = reader.ReadByte() {
-    var ok bool
-
-    if err == io.EOF {
-      if currTag != nil && len(currTag.Name) > 0 {
-        // buff is previous tag's value
-        currTag.Value = string(buff)
-        doctags = append(doctags, currTag)
-        currTag = nil
-      }
-      err = nil
-      break
-    }
-
-    if utf8.RuneStart(b) {
-      column++
-    }
-    buff = append(buff, b)
-
-    if b == '\n' {
-      line++
-      column = 0
-    }
-
-    if b == tagPrefix[0] {
-      if ok,err = consume(reader, tagPrefix); ok {
-        if currTag != nil && len(currTag.Name) > 0 {
-          // buff is previous tag's value (we don't want the first byte of the prefix)
-          currTag.Value = string(buff[:len(buff) - 1])
-          doctags = append(doctags, currTag)
-          currTag = nil
-        } else if currTag != nil {
-          warn(line, column, "doctag open encountered but the previous doctag was not closed properly or has no tag name.")
-        }
-
-        // Create an empty tag
-        currTag = &DoctagNode{Line: line, Column: column}
-        // Clear the buffer
-        buff = make([]byte, 0, bufferSize)
-        // Make sure we take into account the bytes we just consumed
-        column += utf8.RuneCount([]byte(tagSuffix)) - 1
-      }
-    } else if b == tagSuffix[0] && currTag != nil && currTag.Line == line {
-      if len(currTag.Name) == 0 {
-        if ok,err = consume(reader, tagSuffix); ok {
-          // buff is the tag name (we don't want the first byte of the suffix)
-          currTag.Name = strings.TrimSpace(string(buff[:len(buff) - 1]))
-          // Make sure we take into account the bytes we just consumed
-          column += utf8.RuneCount([]byte(tagSuffix)) - 1
-
-          if len(currTag.Name) == 0 {
-            warn(line, column, "doctag close encountered but tag name not detected. Skipping doctag.")
-          } else {
-            // Check to see if we are to skip this tag
-            if currTag.Name[0] == '!' {
-              warn(line, column, fmt.Sprintf("skipping doctag '%v'", currTag.Name))
-              currTag = nil
-            }
-
-            // Clear the buffer
-            buff = make([]byte, 0, bufferSize)
-          }
-        }
-      } else {
-        warn(line, column, "doctag close encountered but the previous doctag was not closed properly or has no tag name.")
-      }
-    }
+// RelativePath returns the path of file relative to the directory dir, and whether file is
+// inside of dir, e.g. to describe the File of a DoctagNode relative to the working directory.
+// Names starting with ".." (e.g. "..notes.txt") are inside of dir.
+func RelativePath(file string, dir string) (string, bool) {
+  abs,err := filepath.Abs(file)
+  if err != nil || len(dir) == 0 {
+    return file,false
   }
 
-  if err != nil {
-    err = fmt.Errorf("Line: %v, Column: %v :: %v", line, column, err.Error())
+  rel,err := filepath.Rel(dir, abs)
+  if err != nil || rel == ".." || strings.HasPrefix(rel, ".." + string(filepath.Separator)) {
+    return file,false
   }
 
-  return
+  return rel,true
 }
 
 // Attempts to consume token from reader.
@@ -224,9 +200,22 @@ func consume(reader *bufio.Reader, token string) (ok bool, err error) {
   return
 }
 
-// Convenient wrapper function that will log a warning message.
-func warn(line int, column int, message string) {
-  if Logger != nil {
-    Logger.Printf("\nLine: %v, Column: %v\n%v\n\n", line, column, message)
+// Attempts to consume the entire token from reader.
+// Nothing is consumed if the reader does not start with token.
+func consumeAll(reader *bufio.Reader, token string) bool {
+  if buff,_ := reader.Peek(len(token)); string(buff) == token {
+    reader.Discard(len(token))
+    return true
+  }
+  return false
+}
+
+// EscapeValue escapes every occurrence of tagPrefix in value by doubling it,
+// so that value is read back literally when written after a doctag.
+// This is the inverse of the unescaping performed while parsing.
+func EscapeValue(value string, tagPrefix string) string {
+  if len(tagPrefix) == 0 {
+    return value
   }
-}
\ No newline at end of file
+  return strings.Replace(value, tagPrefix, tagPrefix + tagPrefix, -1)
+}
@@ -4,6 +4,7 @@ import (
   "testing"
   "log"
   "os"
+  "path/filepath"
 )
 
 func TestParse_SamePrefixAndSuffix(t *testing.T) {
@@ -173,6 +174,27 @@ func TestParse_ComplexWithPrefixAndSuffix(t *testing.T) {
   testSlice(doctags, expected, t)
 }
 
+func TestRelativePath(t *testing.T) {
+  dir := filepath.Join(os.TempDir(), "content")
+
+  inside := map[string]string{
+    filepath.Join(dir, "a.txt"): "a.txt",
+    filepath.Join(dir, "..notes.txt"): "..notes.txt",
+    filepath.Join(dir, "en", "b.txt"): filepath.Join("en", "b.txt"),
+  }
+  for file,expected := range inside {
+    if rel,ok := RelativePath(file, dir); !ok || rel != expected {
+      t.Fatalf("expected '%v' to be inside as '%v' : got '%v', %v", file, expected, rel, ok)
+    }
+  }
+
+  for _,file := range []string{os.TempDir(), filepath.Join(os.TempDir(), "other.txt"), filepath.Join(os.TempDir(), "contents", "a.txt")} {
+    if rel,ok := RelativePath(file, dir); ok || rel != file {
+      t.Fatalf("expected '%v' to be outside : got '%v', %v", file, rel, ok)
+    }
+  }
+}
+
 func testSlice(doctags []*DoctagNode, expected []*DoctagNode, t *testing.T) {
   doctagsLen := len(doctags)
 
@@ -0,0 +1,155 @@
+package parse
+
+import (
+  "os"
+  "io"
+  "log"
+  "strings"
+  "unicode"
+)
+
+// TrimPolicy controls how leading and trailing whitespace is removed from doctag values.
+type TrimPolicy int
+
+// The trim policies supported by a Parser.
+const (
+  // TrimNone preserves all whitespace (the default).
+  TrimNone TrimPolicy = iota
+  // TrimSpace removes leading and trailing whitespace.
+  TrimSpace
+  // TrimLeading removes leading whitespace only.
+  TrimLeading
+  // TrimTrailing removes trailing whitespace only.
+  TrimTrailing
+)
+
+// Apply returns value with whitespace trimmed according to the policy.
+func (policy TrimPolicy) Apply(value string) string {
+  switch policy {
+  case TrimSpace:
+    return strings.TrimSpace(value)
+  case TrimLeading:
+    return strings.TrimLeftFunc(value, unicode.IsSpace)
+  case TrimTrailing:
+    return strings.TrimRightFunc(value, unicode.IsSpace)
+  }
+  return value
+}
+
+// A Parser holds the configuration used to read doctags from documents.
+// A Parser is never modified while parsing, so a single Parser can be shared
+// by many goroutines and differently configured Parsers can run side by side.
+//
+// Use NewParser to create a Parser with the default configuration.
+type Parser struct {
+  // TagPrefix is the substring that opens a doctag.
+  TagPrefix string
+  // TagSuffix is the substring that closes a doctag.
+  TagSuffix string
+  // SkipMarker is the prefix of doctag names that are skipped. The empty string disables skipping.
+  // Closers such as <{!}> are then kept as doctags named "!" (and reported as warnings).
+  SkipMarker string
+  // MultilineNames allows doctag names to span several lines. Runs of whitespace
+  // (including line breaks) in such names are collapsed to a single space, and runs
+  // with a line break next to punctuation (e.g. a separator) are removed.
+  MultilineNames bool
+  // Logger is the optional logger to have warnings logged to.
+  Logger *log.Logger
+  // WarningHandler is the optional function called with every warning found while parsing.
+  // It is called from the goroutine doing the parsing.
+  WarningHandler func(warning *Diagnostic)
+  // Trim is the whitespace policy applied to every doctag value.
+  Trim TrimPolicy
+  // MaxNameLength is the maximum length in bytes of a doctag name. Zero means no limit.
+  MaxNameLength int
+  // MaxValueLength is the maximum length in bytes of a doctag value. Zero means no limit.
+  MaxValueLength int
+  // MaxNodes is the maximum number of doctags in a document. Zero means no limit.
+  MaxNodes int
+}
+
+// NewParser returns a Parser that uses the default prefix, suffix and skip marker.
+func NewParser() *Parser {
+  return &Parser{
+    TagPrefix: DefaultTagPrefix,
+    TagSuffix: DefaultTagSuffix,
+    SkipMarker: DefaultSkipMarker,
+  }
+}
+
+// Validate reports whether the Parser configuration can be used to parse a document.
+// The returned error is a *Diagnostic with the CodeInvalidConfig code.
+func (p *Parser) Validate() error {
+  if p.TagPrefix == p.TagSuffix {
+    return invalidConfig("Tag prefix and suffix cannot be the same.")
+  }
+  if len(p.TagPrefix) == 0 {
+    return invalidConfig("Tag prefix cannot be the empty string.")
+  }
+  if len(p.TagSuffix) == 0 {
+    return invalidConfig("Tag suffix cannot be the empty string.")
+  }
+  if p.MaxNameLength < 0 || p.MaxValueLength < 0 || p.MaxNodes < 0 {
+    return invalidConfig("Parser limits cannot be negative.")
+  }
+  return nil
+}
+
+func invalidConfig(message string) *Diagnostic {
+  return &Diagnostic{Code: CodeInvalidConfig, Severity: SeverityError, Message: message}
+}
+
+// NewScanner returns a Scanner that reads doctags from reader using the Parser configuration.
+// The configuration is copied, so changing the Parser afterwards does not affect the Scanner.
+func (p *Parser) NewScanner(reader io.Reader) *Scanner {
+  return newScanner(reader, *p)
+}
+
+// Parse parses a reader for doctags.
+// The returned slice contains all parsed DoctagNodes in the order they appear in the document.
+// A returned error is a *Diagnostic describing where parsing stopped.
+func (p *Parser) Parse(reader io.Reader) ([]*DoctagNode, error) {
+  return p.parse(p.NewScanner(reader))
+}
+
+// ParseFile parses a text file for doctags.
+// The returned slice contains all parsed DoctagNodes in the order they appear in the document.
+// Diagnostics reported while parsing the file include the file name.
+func (p *Parser) ParseFile(fileName string) ([]*DoctagNode, error) {
+  file,err := os.Open(fileName)
+
+  if err == nil {
+    defer file.Close()
+    scanner := p.NewScanner(file)
+    scanner.file = fileName
+    return p.parse(scanner)
+  }
+
+  return nil,err
+}
+
+func (p *Parser) parse(scanner *Scanner) (doctags []*DoctagNode, err error) {
+  if err = scanner.Err(); err != nil {
+    return
+  }
+
+  doctags = make([]*DoctagNode, 0, 50)
+
+  for scanner.Next() {
+    doctags = append(doctags, scanner.Node())
+  }
+
+  err = scanner.Err()
+
+  return
+}
+
+// The Parser used by the package level functions, configured from the global Logger.
+func defaultParser(tagPrefix string, tagSuffix string) *Parser {
+  return &Parser{
+    TagPrefix: tagPrefix,
+    TagSuffix: tagSuffix,
+    SkipMarker: DefaultSkipMarker,
+    Logger: Logger,
+  }
+}
@@ -0,0 +1,372 @@
+package parse
+
+import (
+  "testing"
+  "strings"
+  "bytes"
+  "log"
+  "sync"
+  "errors"
+)
+
+func TestParser_Trim(t *testing.T) {
+  parser := NewParser()
+  parser.Trim = TrimSpace
+
+  doctags,err := parser.Parse(strings.NewReader("<{a}>\n  one  \n<{b}> two"))
+
+  if err != nil {
+    t.Fatalf("expected no error: %v", err.Error())
+  }
+
+  testSlice(doctags, []*DoctagNode{
+    &DoctagNode{Name: "a", Value: "one", Line: 1, Column: 1},
+    &DoctagNode{Name: "b", Value: "two", Line: 3, Column: 1},
+  }, t)
+}
+
+func TestParser_Limits(t *testing.T) {
+  parser := NewParser()
+  parser.MaxValueLength = 3
+
+  if _,err := parser.Parse(strings.NewReader("<{a}>abc<{b}>abcd")); err == nil {
+    t.Fatalf("expected value length error")
+  }
+
+  parser = NewParser()
+  parser.MaxNameLength = 3
+
+  if _,err := parser.Parse(strings.NewReader("<{abcd}>a")); err == nil {
+    t.Fatalf("expected name length error")
+  }
+
+  parser = NewParser()
+  parser.MaxNodes = 1
+
+  if _,err := parser.Parse(strings.NewReader("<{a}>1<{b}>2")); err == nil {
+    t.Fatalf("expected node count error")
+  }
+}
+
+func TestParser_Concurrent(t *testing.T) {
+  var wg sync.WaitGroup
+
+  configs := []struct {
+    prefix, suffix, document string
+  }{
+    {"<{", "}>", "<{a}>1<{b}>2"},
+    {"[[", "]]", "[[a]]1[[b]]2"},
+    {"-- ", " --", "-- a --1-- b --2"},
+  }
+
+  for _,config := range configs {
+    var out bytes.Buffer
+    parser := &Parser{
+      TagPrefix: config.prefix,
+      TagSuffix: config.suffix,
+      Logger: log.New(&out, "", 0),
+    }
+    document := config.document
+
+    for i := 0; i < 10; i++ {
+      wg.Add(1)
+      go func() {
+        defer wg.Done()
+        doctags,err := parser.Parse(strings.NewReader(document))
+        if err != nil {
+          t.Errorf("expected no error: %v", err.Error())
+        } else if len(doctags) != 2 || doctags[0].Value != "1" || doctags[1].Value != "2" {
+          t.Errorf("unexpected doctags for prefix '%v'", parser.TagPrefix)
+        }
+      }()
+    }
+  }
+
+  wg.Wait()
+}
+
+func TestParser_SkipMarker(t *testing.T) {
+  document := "<{ !important }>1<{ //skip }>2<{//}>"
+
+  parser := NewParser()
+  parser.SkipMarker = "//"
+  doctags,err := parser.Parse(strings.NewReader(document))
+
+  if err != nil {
+    t.Fatalf("expected no error: %v", err.Error())
+  }
+
+  testSlice(doctags, []*DoctagNode{
+    &DoctagNode{Name: "!important", Value: "1", Line: 1, Column: 1},
+  }, t)
+
+  parser.SkipMarker = ""
+  doctags,err = parser.Parse(strings.NewReader(document))
+
+  if err != nil {
+    t.Fatalf("expected no error: %v", err.Error())
+  }
+
+  if len(doctags) != 3 || doctags[1].Name != "//skip" || doctags[2].Name != "//" {
+    t.Fatalf("expected no doctags to be skipped : got %v doctags", len(doctags))
+  }
+
+  // Closers written for the default skip marker are kept and reported.
+  for _,marker := range []string{"", "//"} {
+    warnings := make([]*Diagnostic, 0)
+    parser.SkipMarker = marker
+    parser.WarningHandler = func(warning *Diagnostic) {
+      warnings = append(warnings, warning)
+    }
+
+    doctags,err = parser.Parse(strings.NewReader("<{ a }>1<{!}>\n<{ !b }>2"))
+
+    if err != nil {
+      t.Fatalf("expected no error: %v", err.Error())
+    }
+
+    testSlice(doctags, []*DoctagNode{
+      &DoctagNode{Name: "a", Value: "1", Line: 1, Column: 1},
+      &DoctagNode{Name: "!", Value: "\n", Line: 1, Column: 9},
+      &DoctagNode{Name: "!b", Value: "2", Line: 2, Column: 1},
+    }, t)
+
+    if len(warnings) != 1 || warnings[0].Code != CodeUnskippedCloser || warnings[0].Line != 1 {
+      t.Fatalf("expected a %v warning at line 1 with the marker '%v' : got %v warnings", CodeUnskippedCloser, marker, len(warnings))
+    }
+  }
+}
+
+func TestParser_Escape(t *testing.T) {
+  doctags,err := NewParser().Parse(strings.NewReader("<{a}>Write <{<{ b }> here<{<{<{c}>x"))
+
+  if err != nil {
+    t.Fatalf("expected no error: %v", err.Error())
+  }
+
+  testSlice(doctags, []*DoctagNode{
+    &DoctagNode{Name: "a", Value: "Write <{ b }> here<{", Line: 1, Column: 1},
+    &DoctagNode{Name: "c", Value: "x", Line: 1, Column: 30},
+  }, t)
+}
+
+func TestParser_EscapeOutsideValue(t *testing.T) {
+  warnings := make([]*Diagnostic, 0)
+  parser := NewParser()
+  parser.WarningHandler = func(warning *Diagnostic) {
+    warnings = append(warnings, warning)
+  }
+
+  doctags,err := parser.Parse(strings.NewReader("<{<{ a }>z<{ b }>y"))
+
+  if err != nil {
+    t.Fatalf("expected no error: %v", err.Error())
+  }
+
+  testSlice(doctags, []*DoctagNode{
+    &DoctagNode{Name: "a", Value: "z", Line: 1, Column: 3},
+    &DoctagNode{Name: "b", Value: "y", Line: 1, Column: 11},
+  }, t)
+
+  if len(warnings) != 1 || warnings[0].Code != CodeUnclosedTag {
+    t.Fatalf("expected a %v warning : got %v warnings", CodeUnclosedTag, len(warnings))
+  }
+}
+
+func TestEscapeValue(t *testing.T) {
+  values := []string{"", "<{", "<{<{", "a<{b}>c", "<", "{", "<<{{", "}>"}
+
+  for _,value := range values {
+    document := "<{a}>" + EscapeValue(value, DefaultTagPrefix) + "<{b}>"
+    doctags,err := NewParser().Parse(strings.NewReader(document))
+
+    if err != nil {
+      t.Fatalf("expected no error: %v", err.Error())
+    }
+    if len(doctags) != 2 || doctags[0].Value != value {
+      t.Fatalf("expected value '%v' to round trip : got %v", value, doctags)
+    }
+  }
+}
+
+func TestParser_MultilineNames(t *testing.T) {
+  document := "<{ page/sections/\n   title }>Hello<{a}>b"
+
+  var out bytes.Buffer
+  parser := NewParser()
+  parser.Logger = log.New(&out, "", 0)
+  doctags,err := parser.Parse(strings.NewReader(document))
+
+  if err != nil {
+    t.Fatalf("expected no error: %v", err.Error())
+  }
+  if len(doctags) != 1 || doctags[0].Name != "a" {
+    t.Fatalf("expected the multi-line doctag to be abandoned : got %v doctags", len(doctags))
+  }
+  if !strings.Contains(out.String(), "multi-line names are not enabled") {
+    t.Fatalf("expected a warning about the abandoned doctag : got '%v'", out.String())
+  }
+
+  parser.MultilineNames = true
+  doctags,err = parser.Parse(strings.NewReader(document))
+
+  if err != nil {
+    t.Fatalf("expected no error: %v", err.Error())
+  }
+
+  testSlice(doctags, []*DoctagNode{
+    &DoctagNode{Name: "page/sections/title", Value: "Hello", Line: 1, Column: 1},
+    &DoctagNode{Name: "a", Value: "b", Line: 2, Column: 17},
+  }, t)
+}
+
+func TestParser_MultilineNamesWhitespace(t *testing.T) {
+  parser := NewParser()
+  parser.MultilineNames = true
+
+  names := map[string]string{
+    "page/sections/\n   title": "page/sections/title",
+    "page/sections\r\n  /title": "page/sections/title",
+    "page  /  sections": "page / sections",
+    "Headline\n  four": "Headline four",
+    "page/title\n  lang=en": "page/title",
+  }
+
+  for name,expected := range names {
+    doctags,err := parser.Parse(strings.NewReader("<{ " + name + " }>x"))
+
+    if err != nil {
+      t.Fatalf("expected no error: %v", err.Error())
+    }
+    if len(doctags) != 1 || doctags[0].Name != expected {
+      t.Fatalf("expected the name %q to be %q : got %v", name, expected, doctags)
+    }
+  }
+}
+
+func TestParser_MultilineNamesAfterEmptyName(t *testing.T) {
+  warnings := make([]*Diagnostic, 0)
+  parser := NewParser()
+  parser.MultilineNames = true
+  parser.WarningHandler = func(warning *Diagnostic) {
+    warnings = append(warnings, warning)
+  }
+
+  doctags,err := parser.Parse(strings.NewReader("<{ }>\nsee }> here\n<{ b }>y"))
+
+  if err != nil {
+    t.Fatalf("expected no error: %v", err.Error())
+  }
+
+  testSlice(doctags, []*DoctagNode{
+    &DoctagNode{Name: "b", Value: "y", Line: 3, Column: 1},
+  }, t)
+
+  codes := []Code{CodeEmptyName, CodeUnclosedTag}
+
+  if len(warnings) != len(codes) {
+    t.Fatalf("expected %v warnings : got %v", len(codes), len(warnings))
+  }
+
+  for k,code := range codes {
+    if warnings[k].Code != code {
+      t.Fatalf("expected warning %v to be a %v warning : got %v", k, code, warnings[k].Code)
+    }
+  }
+}
+
+func TestParser_Diagnostics(t *testing.T) {
+  warnings := make([]*Diagnostic, 0)
+  parser := NewParser()
+  parser.WarningHandler = func(warning *Diagnostic) {
+    warnings = append(warnings, warning)
+  }
+  parser.MaxNodes = 1
+
+  _,err := parser.Parse(strings.NewReader("<{}>\n<{ !skip }><{a}>1<{b}>2"))
+
+  var diagnostic *Diagnostic
+  if !errors.As(err, &diagnostic) {
+    t.Fatalf("expected a *Diagnostic error : got %v", err)
+  }
+  if diagnostic.Code != CodeTooManyNodes || diagnostic.Severity != SeverityError {
+    t.Fatalf("expected a %v error : got %v %v", CodeTooManyNodes, diagnostic.Severity, diagnostic.Code)
+  }
+  if diagnostic.Line != 2 || diagnostic.Column != 23 || diagnostic.Offset != 27 {
+    t.Fatalf("expected the error at 2:23 (27) : got %v:%v (%v)", diagnostic.Line, diagnostic.Column, diagnostic.Offset)
+  }
+
+  codes := []Code{CodeEmptyName, CodeUnclosedTag, CodeSkippedTag}
+
+  if len(warnings) != len(codes) {
+    t.Fatalf("expected %v warnings : got %v", len(codes), len(warnings))
+  }
+
+  for k,code := range codes {
+    if warnings[k].Code != code || warnings[k].Severity != SeverityWarning {
+      t.Fatalf("expected warning %v to be a %v warning : got %v", k, code, warnings[k].Code)
+    }
+  }
+}
+
+func TestParser_Spans(t *testing.T) {
+  document := "intro\n<{ a }>\n世界 <{<{x\n<{b}>end"
+  doctags,err := NewParser().Parse(strings.NewReader(document))
+
+  if err != nil {
+    t.Fatalf("expected no error: %v", err.Error())
+  }
+  if len(doctags) != 2 {
+    t.Fatalf("expected 2 doctags : got %v", len(doctags))
+  }
+
+  expected := []struct {
+    tag, value string
+    tagSpan, valueSpan Span
+  }{
+    {
+      "<{ a }>", "\n世界 <{<{x\n",
+      Span{Position{6, 2, 1}, Position{13, 2, 8}},
+      Span{Position{13, 2, 8}, Position{27, 4, 1}},
+    },
+    {
+      "<{b}>", "end",
+      Span{Position{27, 4, 1}, Position{32, 4, 6}},
+      Span{Position{32, 4, 6}, Position{35, 4, 9}},
+    },
+  }
+
+  for k,e := range expected {
+    doctag := doctags[k]
+    if doctag.Tag != e.tagSpan {
+      t.Fatalf("expected tag %v to span %v : got %v", k, e.tagSpan, doctag.Tag)
+    }
+    if doctag.ValueSpan != e.valueSpan {
+      t.Fatalf("expected value %v to span %v : got %v", k, e.valueSpan, doctag.ValueSpan)
+    }
+    if text := document[doctag.Tag.Start.Offset:doctag.Tag.End.Offset]; text != e.tag {
+      t.Fatalf("expected tag %v to be '%v' : got '%v'", k, e.tag, text)
+    }
+    if text := document[doctag.ValueSpan.Start.Offset:doctag.ValueSpan.End.Offset]; text != e.value {
+      t.Fatalf("expected value %v to be '%v' : got '%v'", k, e.value, text)
+    }
+  }
+}
+
+func TestParser_File(t *testing.T) {
+  doctags,err := NewParser().ParseFile("./fixtures/complex.txt")
+
+  if err != nil {
+    t.Fatalf("expected no error: %v", err.Error())
+  }
+
+  for _,doctag := range doctags {
+    if doctag.File != "./fixtures/complex.txt" {
+      t.Fatalf("expected the doctag '%v' to have a file : got '%v'", doctag.Name, doctag.File)
+    }
+  }
+
+  if doctags,err = NewParser().Parse(strings.NewReader("<{a}>b")); err != nil || doctags[0].File != "" {
+    t.Fatalf("expected no file for a doctag read from a reader : got %v", doctags)
+  }
+}
@@ -0,0 +1,326 @@
+package parse
+
+import (
+  "io"
+  "fmt"
+  "bufio"
+  "strings"
+  "unicode"
+  "unicode/utf8"
+)
+
+// The capacity to create text buffers at (i.e. to capture text between doctags).
+const bufferSize = 512
+
+// A Scanner reads doctags from a reader one DoctagNode at a time.
+// Unlike the Parse functions, a Scanner never holds more than the
+// doctag currently being read in memory, which makes it suitable
+// for very large documents.
+//
+// Typical usage:
+//
+//  scanner := parse.NewScanner(reader)
+//  for scanner.Next() {
+//    doctag := scanner.Node()
+//    ...
+//  }
+//  if err := scanner.Err(); err != nil {
+//    ...
+//  }
+type Scanner struct {
+  reader *bufio.Reader
+  config Parser
+  buff []byte
+  file string
+  line int
+  column int
+  // The position of the next byte to be read
+  pos Position
+  count int
+  currTag *DoctagNode
+  // Whether the suffix of currTag has been read (its name may still be empty)
+  currTagClosed bool
+  node *DoctagNode
+  err error
+  done bool
+}
+
+// NewScanner returns a Scanner that reads doctags from reader using the default prefix and suffix substrings.
+func NewScanner(reader io.Reader) *Scanner {
+  return NewScannerWithPrefixAndSuffix(reader, DefaultTagPrefix, DefaultTagSuffix)
+}
+
+// NewScannerWithPrefixAndSuffix returns a Scanner that reads doctags from reader using custom prefix and suffix substrings for doctags.
+func NewScannerWithPrefixAndSuffix(reader io.Reader, tagPrefix string, tagSuffix string) *Scanner {
+  return defaultParser(tagPrefix, tagSuffix).NewScanner(reader)
+}
+
+func newScanner(reader io.Reader, config Parser) *Scanner {
+  s := &Scanner{
+    config: config,
+    buff: make([]byte, 0, bufferSize),
+    line: 1,
+    pos: Position{Line: 1, Column: 1},
+  }
+
+  if r,ok := reader.(*bufio.Reader); ok {
+    s.reader = r
+  } else {
+    s.reader = bufio.NewReader(reader)
+  }
+
+  if s.err = config.Validate(); s.err != nil {
+    s.done = true
+  }
+
+  return s
+}
+
+// Next advances the scanner to the next DoctagNode, which will then be available through the Node method.
+// It returns false when the scan stops, either by reaching the end of the input or an error.
+// After Next returns false, the Err method will return any error that occurred during scanning.
+func (s *Scanner) Next() bool {
+  s.node = nil
+
+  if s.done {
+    return false
+  }
+
+  var (
+    b byte
+    err error
+  )
+
+  for b,err = s.reader.ReadByte(); err == nil || err == io.EOF; b,err = s.reader.ReadByte() {
+    var ok bool
+
+    if err == io.EOF {
+      s.done = true
+      if s.currTag != nil && len(s.currTag.Name) > 0 {
+        // buff is previous tag's value
+        return s.emit(s.buff, s.pos)
+      }
+      return false
+    }
+
+    // The position of b, which is where a doctag starts or a value ends
+    start := s.pos
+    s.advance(b)
+
+    if utf8.RuneStart(b) {
+      s.column++
+    }
+    s.buff = append(s.buff, b)
+
+    // Fail early rather than buffering an oversized value (allowing for the first byte of a prefix).
+    if s.config.MaxValueLength > 0 && s.currTag != nil && len(s.currTag.Name) > 0 && len(s.buff) > s.config.MaxValueLength + 1 {
+      return s.fail(CodeValueTooLong, fmt.Sprintf("value of doctag '%v' exceeds the maximum length of %v bytes", s.currTag.Name, s.config.MaxValueLength), nil)
+    }
+
+    if b == '\n' {
+      if !s.config.MultilineNames && s.currTag != nil && !s.currTagClosed && s.currTag.Line == s.line {
+        s.warn(CodeMultilineName, "doctag name continues onto the next line, but multi-line names are not enabled. Abandoning doctag.")
+      }
+      s.line++
+      s.column = 0
+    }
+
+    if b == s.config.TagPrefix[0] {
+      if ok,err = s.consume(s.config.TagPrefix); ok && s.currTag != nil && len(s.currTag.Name) > 0 && s.consumeAll(s.config.TagPrefix) {
+        // A doubled prefix in a value is an escaped literal prefix (we already have the first byte of the prefix)
+        s.buff = append(s.buff, s.config.TagPrefix[1:]...)
+        s.column += 2 * utf8.RuneCountInString(s.config.TagPrefix) - 1
+      } else if ok {
+        if s.currTag != nil && len(s.currTag.Name) > 0 {
+          // buff is previous tag's value (we don't want the first byte of the prefix)
+          if !s.emit(s.buff[:len(s.buff) - 1], start) {
+            return false
+          }
+        } else if s.currTag != nil {
+          s.warn(CodeUnclosedTag, "doctag open encountered but the previous doctag was not closed properly or has no tag name.")
+        }
+
+        // Create an empty tag
+        s.currTag = &DoctagNode{Line: s.line, Column: s.column, Tag: Span{Start: start}, File: s.file}
+        s.currTagClosed = false
+        // Clear the buffer
+        s.buff = make([]byte, 0, bufferSize)
+        // Make sure we take into account the bytes we just consumed
+        s.column += utf8.RuneCount([]byte(s.config.TagSuffix)) - 1
+
+        if s.node != nil {
+          return true
+        }
+      }
+    } else if b == s.config.TagSuffix[0] && s.currTag != nil && !s.currTagClosed && (s.config.MultilineNames || s.currTag.Line == s.line) {
+      if ok,err = s.consume(s.config.TagSuffix); ok {
+        s.currTag.Tag.End = s.pos
+        s.currTag.ValueSpan.Start = s.pos
+        s.currTagClosed = true
+        // buff is the tag name (we don't want the first byte of the suffix)
+        s.currTag.Name = strings.TrimSpace(string(s.buff[:len(s.buff) - 1]))
+        if s.config.MultilineNames {
+          s.currTag.Name = normalizeName(s.currTag.Name)
+        }
+        s.currTag.Name,s.currTag.Attributes = SplitAttributes(s.currTag.Name)
+        // Make sure we take into account the bytes we just consumed
+        s.column += utf8.RuneCount([]byte(s.config.TagSuffix)) - 1
+
+        if s.config.MaxNameLength > 0 && len(s.currTag.Name) > s.config.MaxNameLength {
+          return s.fail(CodeNameTooLong, fmt.Sprintf("doctag name exceeds the maximum length of %v bytes", s.config.MaxNameLength), nil)
+        }
+
+        if len(s.currTag.Name) == 0 {
+          s.warn(CodeEmptyName, "doctag close encountered but tag name not detected. Skipping doctag.")
+        } else if len(s.config.SkipMarker) > 0 && strings.HasPrefix(s.currTag.Name, s.config.SkipMarker) {
+          // Check to see if we are to skip this tag
+          s.warn(CodeSkippedTag, fmt.Sprintf("skipping doctag '%v'", s.currTag.Name))
+          s.currTag = nil
+        } else if s.currTag.Name == DefaultSkipMarker {
+          s.warn(CodeUnskippedCloser, fmt.Sprintf("doctag '%v' is not skipped since skipping is disabled or uses another marker, it is kept as a doctag named '%v'", s.currTag.Name, s.currTag.Name))
+        }
+
+        // Clear the buffer (the text following a tag without a name is never a name or a value)
+        s.buff = make([]byte, 0, bufferSize)
+      }
+    } else if b == s.config.TagSuffix[0] && s.currTag != nil && s.currTag.Line == s.line {
+      s.warn(CodeStraySuffix, "doctag close encountered but the previous doctag was not closed properly or has no tag name.")
+    }
+  }
+
+  return s.fail(CodeRead, err.Error(), err)
+}
+
+// Collapses the whitespace (including line breaks) used to wrap a name to a single space.
+// Whitespace with a line break next to punctuation, such as the separator in
+// "page/sections/\n  title", is removed so that the name reads "page/sections/title".
+func normalizeName(name string) string {
+  var b strings.Builder
+
+  for len(name) > 0 {
+    start := strings.IndexFunc(name, unicode.IsSpace)
+    if start < 0 {
+      b.WriteString(name)
+      break
+    }
+    b.WriteString(name[:start])
+
+    end := strings.IndexFunc(name[start:], func (r rune) bool { return !unicode.IsSpace(r) })
+    if end < 0 {
+      break
+    }
+    space := name[start:start + end]
+    name = name[start + end:]
+
+    prev,_ := utf8.DecodeLastRuneInString(b.String())
+    next,_ := utf8.DecodeRuneInString(name)
+    if !strings.Contains(space, "\n") || !(unicode.IsPunct(prev) || unicode.IsPunct(next)) {
+      b.WriteByte(' ')
+    }
+  }
+
+  return b.String()
+}
+
+// Advances the position over the byte b.
+func (s *Scanner) advance(b byte) {
+  s.pos.Offset++
+  if b == '\n' {
+    s.pos.Line++
+    s.pos.Column = 1
+  } else if utf8.RuneStart(b) {
+    s.pos.Column++
+  }
+}
+
+// Attempts to consume token (see consume()), advancing the position over the consumed bytes.
+func (s *Scanner) consume(token string) (ok bool, err error) {
+  if ok,err = consume(s.reader, token); ok {
+    _,firstRuneSize := utf8.DecodeRuneInString(token)
+    for k := firstRuneSize; k < len(token); k++ {
+      s.advance(token[k])
+    }
+  }
+  return
+}
+
+// Attempts to consume the entire token (see consumeAll()), advancing the position over it.
+func (s *Scanner) consumeAll(token string) bool {
+  if consumeAll(s.reader, token) {
+    for k := 0; k < len(token); k++ {
+      s.advance(token[k])
+    }
+    return true
+  }
+  return false
+}
+
+// Completes the current tag with value ending at end and makes it available through Node().
+func (s *Scanner) emit(value []byte, end Position) bool {
+  s.count++
+  if s.config.MaxNodes > 0 && s.count > s.config.MaxNodes {
+    return s.fail(CodeTooManyNodes, fmt.Sprintf("document exceeds the maximum of %v doctags", s.config.MaxNodes), nil)
+  }
+  if s.config.MaxValueLength > 0 && len(value) > s.config.MaxValueLength {
+    return s.fail(CodeValueTooLong, fmt.Sprintf("value of doctag '%v' exceeds the maximum length of %v bytes", s.currTag.Name, s.config.MaxValueLength), nil)
+  }
+
+  s.currTag.Value = s.config.Trim.Apply(string(value))
+  s.currTag.ValueSpan.End = end
+  s.node = s.currTag
+  s.currTag = nil
+
+  return true
+}
+
+// Stops the scan and records an error diagnostic at the current position.
+func (s *Scanner) fail(code Code, message string, err error) bool {
+  s.done = true
+  s.node = nil
+  s.err = s.diagnostic(SeverityError, code, message, err)
+  return false
+}
+
+// Convenient wrapper function that will report a warning at the current position.
+func (s *Scanner) warn(code Code, message string) {
+  if s.config.Logger == nil && s.config.WarningHandler == nil {
+    return
+  }
+
+  warning := s.diagnostic(SeverityWarning, code, message, nil)
+
+  if s.config.Logger != nil {
+    s.config.Logger.Printf("\nLine: %v, Column: %v\n%v\n\n", warning.Line, warning.Column, warning.Message)
+  }
+  if s.config.WarningHandler != nil {
+    s.config.WarningHandler(warning)
+  }
+}
+
+func (s *Scanner) diagnostic(severity Severity, code Code, message string, err error) *Diagnostic {
+  offset := s.pos.Offset - 1
+  if offset < 0 {
+    offset = 0
+  }
+
+  return &Diagnostic{
+    Code: code,
+    Severity: severity,
+    File: s.file,
+    Line: s.line,
+    Column: s.column,
+    Offset: offset,
+    Message: message,
+    Err: err,
+  }
+}
+
+// Node returns the most recent DoctagNode read by a call to Next.
+func (s *Scanner) Node() *DoctagNode {
+  return s.node
+}
+
+// Err returns the first error that was encountered by the Scanner.
+func (s *Scanner) Err() error {
+  return s.err
+}
@@ -0,0 +1,70 @@
+package parse
+
+import (
+  "testing"
+  "strings"
+  "os"
+)
+
+func TestScanner_Complex(t *testing.T) {
+  var expected = []*DoctagNode {
+    &DoctagNode{Name: "Headline", Value: "\nThis is a headline\n\n", Line: 1, Column: 1},
+    &DoctagNode{Name: "Headline2", Value: "\nThis is a headline\n\n  ", Line: 4, Column: 13},
+    &DoctagNode{Name: "Headline3", Value: "\nThis is a headline\nThis is another line\n\n\n", Line: 7, Column: 3},
+    &DoctagNode{Name: "Headline4", Value: " Headline 4 ", Line: 12, Column: 1},
+    &DoctagNode{Name: "Headline4 / Link", Value: " Headline 4 Link\n", Line: 12, Column: 26},
+    &DoctagNode{Name: "Broke", Value: "}>Boom\n", Line: 13, Column: 1},
+    &DoctagNode{Name: "hello", Value: "hello", Line: 15, Column: 1},
+    &DoctagNode{Name: "hi", Value: "\nhi", Line: 16, Column: 1},
+  }
+
+  file,err := os.Open("./fixtures/complex.txt")
+  if err != nil {
+    t.Fatalf("expected no error: %v", err.Error())
+  }
+  defer file.Close()
+
+  doctags := make([]*DoctagNode, 0, len(expected))
+  scanner := NewScanner(file)
+
+  for scanner.Next() {
+    doctags = append(doctags, scanner.Node())
+  }
+
+  if err := scanner.Err(); err != nil {
+    t.Fatalf("expected no error: %v", err.Error())
+  }
+
+  testSlice(doctags, expected, t)
+}
+
+func TestScanner_SamePrefixAndSuffix(t *testing.T) {
+  scanner := NewScannerWithPrefixAndSuffix(strings.NewReader("!a!b"), "!", "!")
+
+  if scanner.Next() {
+    t.Fatalf("expected no doc tags to be found: found %v", scanner.Node().Name)
+  }
+
+  if scanner.Err() == nil {
+    t.Fatalf("expected error")
+  }
+}
+
+func TestScanner_NodeAfterEnd(t *testing.T) {
+  scanner := NewScanner(strings.NewReader("<{a}>1<{b}>2"))
+  names := ""
+
+  for scanner.Next() {
+    names += scanner.Node().Name
+  }
+
+  if names != "ab" {
+    t.Fatalf("expected doctags 'ab' : got '%v'", names)
+  }
+  if scanner.Node() != nil {
+    t.Fatalf("expected no doctag after the scan stopped")
+  }
+  if scanner.Next() {
+    t.Fatalf("expected Next() to keep returning false")
+  }
+}
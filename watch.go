@@ -0,0 +1,51 @@
+package main
+
+import (
+  "fmt"
+  "os"
+  "time"
+  "strings"
+)
+
+// Polls the input files and runs the command whenever they change, until interrupted.
+// Errors are reported without stopping, and the output is kept until the next successful run.
+func doWatch() {
+  var last string
+
+  for {
+    if state := watchState(); state != last {
+      last = state
+      if err := run(); err != nil && err != errReported {
+        reportError(err)
+      } else if err == nil && len(output) > 0 && diagnostics == "text" {
+        fmt.Fprintf(os.Stderr, "doctag: wrote %v\n", output)
+      }
+    }
+    time.Sleep(watchInterval)
+  }
+}
+
+// Describes the size and modification time of every input file, so that changes
+// (including added and removed files) are noticed by comparing descriptions.
+func watchState() string {
+  paths := inputs
+  if len(paths) == 0 {
+    paths = []string{fileName}
+  }
+
+  files,err := expandInputs(paths)
+  if err != nil {
+    return err.Error()
+  }
+
+  var b strings.Builder
+  for _,file := range files {
+    if info,err := os.Stat(file); err == nil {
+      fmt.Fprintf(&b, "%v %v %v\n", file, info.Size(), info.ModTime().UnixNano())
+    } else {
+      fmt.Fprintf(&b, "%v %v\n", file, err.Error())
+    }
+  }
+
+  return b.String()
+}
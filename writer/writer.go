@@ -0,0 +1,251 @@
+/*
+Package writer serializes DoctagNodes back into a doctag document. It is the
+inverse of the parse package: parsing the written document with the same
+prefix, suffix and skip marker results in DoctagNodes with the same names,
+values and attributes as the ones that were written.
+
+Values are written exactly as they are, so leading and trailing whitespace is
+preserved. Tag prefixes found in values are escaped by doubling them.
+
+Example:
+
+  writer.Write(os.Stdout, []*parse.DoctagNode{
+    &parse.DoctagNode{Name: "page/title", Value: "Today's News Stories"},
+    &parse.DoctagNode{Name: "page/content", Value: "\nBlah ablah blab ablaha bal.\n"},
+  })
+
+Writes the document:
+
+  <{ page/title }>Today's News Stories<{ page/content }>
+  Blah ablah blab ablaha bal.
+
+When the Closers field of a Writer is set, every value is followed by a
+skipped doctag (e.g. <{!}>) and a line break, which can be easier to read:
+
+  <{ page/title }>Today's News Stories<{!}>
+  <{ page/content }>
+  Blah ablah blab ablaha bal.
+  <{!}>
+
+Some DoctagNodes cannot be written such that they are parsed back the same,
+for example a node with an empty name or a name that contains the tag suffix.
+Writing such a node results in an error and nothing is written at all, since
+every node is formatted before the document is written.
+*/
+package writer
+
+import (
+  "io"
+  "fmt"
+  "sort"
+  "bufio"
+  "errors"
+  "strings"
+  "unicode"
+  "github.com/dschnare/doctag/parse"
+)
+
+// A Writer holds the configuration used to write doctag documents.
+// Use NewWriter to create a Writer with the default configuration.
+type Writer struct {
+  // TagPrefix is the substring that opens a doctag.
+  TagPrefix string
+  // TagSuffix is the substring that closes a doctag.
+  TagSuffix string
+  // SkipMarker is the skip marker of the parser that will read the document.
+  // It is used to write closers and names starting with it cannot be written.
+  SkipMarker string
+  // Closers writes a skipped doctag and a line break after every value.
+  Closers bool
+  // Compact writes doctags without spaces between the name and the prefix and suffix.
+  Compact bool
+}
+
+// NewWriter returns a Writer that uses the default prefix, suffix and skip marker.
+func NewWriter() *Writer {
+  return &Writer{
+    TagPrefix: parse.DefaultTagPrefix,
+    TagSuffix: parse.DefaultTagSuffix,
+    SkipMarker: parse.DefaultSkipMarker,
+  }
+}
+
+// Write writes doctags to out using the default prefix, suffix and skip marker.
+func Write(out io.Writer, doctags []*parse.DoctagNode) error {
+  return NewWriter().Write(out, doctags)
+}
+
+// WriteWithPrefixAndSuffix writes doctags to out using custom prefix and suffix substrings for doctags.
+func WriteWithPrefixAndSuffix(out io.Writer, doctags []*parse.DoctagNode, tagPrefix string, tagSuffix string) error {
+  w := NewWriter()
+  w.TagPrefix = tagPrefix
+  w.TagSuffix = tagSuffix
+  return w.Write(out, doctags)
+}
+
+// Validate reports whether the Writer configuration can be used to write a document.
+func (w *Writer) Validate() error {
+  if w.TagPrefix == w.TagSuffix {
+    return errors.New("Tag prefix and suffix cannot be the same.")
+  }
+  if len(w.TagPrefix) == 0 {
+    return errors.New("Tag prefix cannot be the empty string.")
+  }
+  if len(w.TagSuffix) == 0 {
+    return errors.New("Tag suffix cannot be the empty string.")
+  }
+  if w.Closers && len(w.SkipMarker) == 0 {
+    return errors.New("Closers cannot be written without a skip marker.")
+  }
+  return nil
+}
+
+// Write writes doctags to out in order. Nothing is written when a doctag cannot be written,
+// the error describes the first one.
+func (w *Writer) Write(out io.Writer, doctags []*parse.DoctagNode) (err error) {
+  if err = w.Validate(); err != nil {
+    return
+  }
+
+  texts := make([]string, len(doctags))
+
+  for k,doctag := range doctags {
+    if texts[k],err = w.Format(doctag); err != nil {
+      return fmt.Errorf("doctag %v (%v) :: %v", k, doctag.Name, err.Error())
+    }
+  }
+
+  buff := bufio.NewWriter(out)
+
+  for _,text := range texts {
+    if _,err = buff.WriteString(text); err != nil {
+      return
+    }
+  }
+
+  return buff.Flush()
+}
+
+// Format returns the text written for a single doctag, including its value and closer.
+func (w *Writer) Format(doctag *parse.DoctagNode) (string, error) {
+  tag,err := w.formatTag(doctag)
+  if err != nil {
+    return "",err
+  }
+
+  value := parse.EscapeValue(doctag.Value, w.TagPrefix)
+
+  // The value may be followed by a prefix (the next doctag or a closer),
+  // so make sure the end of the value can't be read as a prefix.
+  if overlaps(value, w.TagPrefix) {
+    return "",errors.New("value ends with part of the tag prefix")
+  }
+
+  text := tag + value
+
+  if w.Closers {
+    text += w.TagPrefix + w.SkipMarker + w.TagSuffix + "\n"
+  }
+
+  return text,nil
+}
+
+// Formats the doctag itself (prefix, name, attributes and suffix).
+func (w *Writer) formatTag(doctag *parse.DoctagNode) (string, error) {
+  name := doctag.Name
+
+  if len(name) == 0 {
+    return "",errors.New("name cannot be empty")
+  }
+  if strings.TrimSpace(name) != name {
+    return "",errors.New("name cannot start or end with whitespace")
+  }
+  if strings.ContainsAny(name, "\r\n") {
+    return "",errors.New("name cannot contain line breaks")
+  }
+  if len(w.SkipMarker) > 0 && strings.HasPrefix(name, w.SkipMarker) {
+    return "",fmt.Errorf("name cannot start with the skip marker '%v'", w.SkipMarker)
+  }
+
+  text := name
+  keys := make([]string, 0, len(doctag.Attributes))
+
+  for key := range doctag.Attributes {
+    keys = append(keys, key)
+  }
+  sort.Strings(keys)
+
+  for _,key := range keys {
+    attribute,err := formatAttribute(key, doctag.Attributes[key])
+    if err != nil {
+      return "",err
+    }
+    text += " " + attribute
+  }
+
+  // Make sure the text is read back as the same name and attributes.
+  if n,attributes := parse.SplitAttributes(text); n != name || !sameAttributes(attributes, doctag.Attributes) {
+    return "",errors.New("name cannot contain a field with '=' after its first field")
+  }
+
+  if !w.Compact {
+    text = " " + text + " "
+  }
+
+  if strings.Contains(text + w.TagSuffix, w.TagPrefix) {
+    return "",errors.New("name and attributes cannot contain the tag prefix")
+  }
+  if strings.Index(text + w.TagSuffix, w.TagSuffix) != len(text) {
+    return "",errors.New("name and attributes cannot contain the tag suffix")
+  }
+
+  return w.TagPrefix + text + w.TagSuffix,nil
+}
+
+// Formats an attribute as key=value, quoting the value when required.
+func formatAttribute(key string, value string) (string, error) {
+  if len(key) == 0 || strings.ContainsRune(key, '=') || strings.IndexFunc(key, unicode.IsSpace) >= 0 {
+    return "",fmt.Errorf("attribute key '%v' cannot be empty or contain '=' or whitespace", key)
+  }
+  if strings.ContainsAny(value, "\r\n") {
+    return "",fmt.Errorf("attribute '%v' cannot contain line breaks", key)
+  }
+
+  if len(value) == 0 || strings.HasPrefix(value, `"`) || strings.IndexFunc(value, unicode.IsSpace) >= 0 {
+    if strings.ContainsRune(value, '"') {
+      return "",fmt.Errorf("attribute '%v' needs quotes but contains '\"'", key)
+    }
+    value = `"` + value + `"`
+  }
+
+  return key + "=" + value,nil
+}
+
+// Reports whether the end of value followed by prefix would be read as an earlier prefix.
+func overlaps(value string, prefix string) bool {
+  start := len(value) - len(prefix) + 1
+  if start < 0 {
+    start = 0
+  }
+
+  for k := start; k < len(value); k++ {
+    if strings.HasPrefix(value[k:] + prefix, prefix) {
+      return true
+    }
+  }
+
+  return false
+}
+
+// Compares attributes, treating nil and empty maps as equal.
+func sameAttributes(a map[string]string, b map[string]string) bool {
+  if len(a) != len(b) {
+    return false
+  }
+  for key,value := range a {
+    if v,ok := b[key]; !ok || v != value {
+      return false
+    }
+  }
+  return true
+}
@@ -0,0 +1,157 @@
+package writer
+
+import (
+  "testing"
+  "testing/quick"
+  "bytes"
+  "strings"
+  "reflect"
+  "math/rand"
+  "github.com/dschnare/doctag/parse"
+)
+
+func TestWrite(t *testing.T) {
+  doctags := []*parse.DoctagNode{
+    &parse.DoctagNode{Name: "page/title", Value: "Today's News Stories", Attributes: map[string]string{"lang": "en"}},
+    &parse.DoctagNode{Name: "page/content", Value: "\nWrite <{ a }> for a tag.\n"},
+  }
+
+  var out bytes.Buffer
+  if err := Write(&out, doctags); err != nil {
+    t.Fatalf("expected no error: %v", err.Error())
+  }
+
+  expected := "<{ page/title lang=en }>Today's News Stories<{ page/content }>\nWrite <{<{ a }> for a tag.\n"
+  if out.String() != expected {
+    t.Fatalf("expected '%v' : got '%v'", expected, out.String())
+  }
+
+  w := NewWriter()
+  w.Closers = true
+  w.Compact = true
+  out.Reset()
+  if err := w.Write(&out, doctags[1:]); err != nil {
+    t.Fatalf("expected no error: %v", err.Error())
+  }
+
+  expected = "<{page/content}>\nWrite <{<{ a }> for a tag.\n<{!}>\n"
+  if out.String() != expected {
+    t.Fatalf("expected '%v' : got '%v'", expected, out.String())
+  }
+}
+
+func TestWrite_Invalid(t *testing.T) {
+  invalid := []*parse.DoctagNode{
+    &parse.DoctagNode{Name: ""},
+    &parse.DoctagNode{Name: " a"},
+    &parse.DoctagNode{Name: "a\nb"},
+    &parse.DoctagNode{Name: "!a"},
+    &parse.DoctagNode{Name: "a}>b"},
+    &parse.DoctagNode{Name: "a<{b"},
+    &parse.DoctagNode{Name: "a b=c"},
+    &parse.DoctagNode{Name: "a", Attributes: map[string]string{"b c": "d"}},
+    &parse.DoctagNode{Name: "a", Attributes: map[string]string{"b": "\"c d\""}},
+  }
+
+  for _,doctag := range invalid {
+    var out bytes.Buffer
+    if err := Write(&out, []*parse.DoctagNode{doctag}); err == nil {
+      t.Fatalf("expected an error writing doctag '%v' : got '%v'", doctag.Name, out.String())
+    }
+  }
+
+  // Nothing is written when any of the doctags cannot be written.
+  var out bytes.Buffer
+  if err := Write(&out, []*parse.DoctagNode{&parse.DoctagNode{Name: "a", Value: "b"}, invalid[0]}); err == nil || out.Len() > 0 {
+    t.Fatalf("expected an error and no output : got %v, '%v'", err, out.String())
+  }
+}
+
+// A document used to check that parse(write(doctags)) == doctags.
+type document []*parse.DoctagNode
+
+// The alphabet is made of runes found in prefixes, suffixes, attributes and whitespace.
+var alphabet = []rune("ab/# =\"!<{}>-[]\n\t世")
+
+func randomString(r *rand.Rand, maxLength int) string {
+  runes := make([]rune, r.Intn(maxLength + 1))
+  for k := range runes {
+    runes[k] = alphabet[r.Intn(len(alphabet))]
+  }
+  return string(runes)
+}
+
+func (document) Generate(r *rand.Rand, size int) reflect.Value {
+  doctags := make(document, r.Intn(5))
+
+  for k := range doctags {
+    doctag := &parse.DoctagNode{
+      Name: strings.TrimSpace(randomString(r, 8)),
+      Value: randomString(r, 20),
+    }
+    if r.Intn(3) == 0 {
+      doctag.Attributes = map[string]string{randomString(r, 3): randomString(r, 5)}
+    }
+    doctags[k] = doctag
+  }
+
+  return reflect.ValueOf(doctags)
+}
+
+func TestWrite_RoundTrip(t *testing.T) {
+  configs := []struct {
+    prefix, suffix string
+    closers, compact bool
+  }{
+    {parse.DefaultTagPrefix, parse.DefaultTagSuffix, false, false},
+    {parse.DefaultTagPrefix, parse.DefaultTagSuffix, true, true},
+    {"-- ", " --", false, true},
+    {"[[", "]]", true, false},
+  }
+
+  for _,config := range configs {
+    w := NewWriter()
+    w.TagPrefix = config.prefix
+    w.TagSuffix = config.suffix
+    w.Closers = config.closers
+    w.Compact = config.compact
+
+    parser := parse.NewParser()
+    parser.TagPrefix = config.prefix
+    parser.TagSuffix = config.suffix
+
+    written := 0
+
+    roundTrip := func(doctags document) bool {
+      var out bytes.Buffer
+      if err := w.Write(&out, doctags); err != nil {
+        // Only documents that can be written must round trip.
+        return true
+      }
+      written++
+
+      parsed,err := parser.Parse(strings.NewReader(out.String()))
+      if err != nil || len(parsed) != len(doctags) {
+        t.Logf("document %q parsed as %v doctags : expected %v (%v)", out.String(), len(parsed), len(doctags), err)
+        return false
+      }
+
+      for k,doctag := range doctags {
+        if parsed[k].Name != doctag.Name || parsed[k].Value != doctag.Value || !sameAttributes(parsed[k].Attributes, doctag.Attributes) {
+          t.Logf("document %q parsed doctag %v as %q %q %v : expected %q %q %v", out.String(), k,
+            parsed[k].Name, parsed[k].Value, parsed[k].Attributes, doctag.Name, doctag.Value, doctag.Attributes)
+          return false
+        }
+      }
+
+      return true
+    }
+
+    if err := quick.Check(roundTrip, &quick.Config{MaxCount: 2000}); err != nil {
+      t.Fatalf("prefix '%v' and suffix '%v' : %v", config.prefix, config.suffix, err)
+    }
+    if written < 100 {
+      t.Fatalf("expected at least 100 documents to be written : got %v", written)
+    }
+  }
+}